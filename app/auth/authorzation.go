@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -13,64 +15,148 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-var (
-	accessSecret  = []byte("132")
-	refreshSecret = []byte("123")
-	AccessTTL     = 5 * time.Minute
-	RefreshTTL    = 24 * time.Hour
-)
+type Config struct {
+	Algorithm         string
+	Issuer            string
+	AccessSecret      string
+	AccessSecretFile  string
+	RefreshSecret     string
+	RefreshSecretFile string
+	PrivateKeyFile    string
+	AccessTTL         time.Duration
+	RefreshTTL        time.Duration
+}
+
+// Manager emite e valida os tokens. Access tokens usam o algoritmo
+// configurado (HS256, RS256 ou EdDSA); refresh tokens só são lidos por
+// esta aplicação e por isso usam sempre HS256 com um segredo próprio.
+//
+// As chaves dos access tokens ficam num keyring: a chave ativa assina e
+// é identificada pelo header kid, e as chaves em aposentadoria seguem
+// válidas para validação até que os tokens emitidos por elas expirem.
+type Manager struct {
+	keys        *keyring
+	store       KeyStore
+	sessions    SessionStore
+	revocations RevocationStore
+	tickets     TicketStore
+	refresh     *signingKey
+	algorithm   string
+	issuer      string
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+func NewManager(cfg Config, store KeyStore, sessions SessionStore, revocations RevocationStore, tickets TicketStore) (*Manager, error) {
+	access, err := newAccessKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := newHMACKey(cfg.RefreshSecret, cfg.RefreshSecretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		keys:        newKeyring(access),
+		store:       store,
+		sessions:    sessions,
+		revocations: revocations,
+		tickets:     tickets,
+		refresh:     refresh,
+		algorithm:   access.method.Alg(),
+		issuer:      cfg.Issuer,
+		AccessTTL:   cfg.AccessTTL,
+		RefreshTTL:  cfg.RefreshTTL,
+	}
+	if m.issuer == "" {
+		m.issuer = "chat-app"
+	}
+	if m.AccessTTL <= 0 {
+		m.AccessTTL = 5 * time.Minute
+	}
+	if m.RefreshTTL <= 0 {
+		m.RefreshTTL = 24 * time.Hour
+	}
+	return m, nil
+}
+
+// GenerateAccessToken emite o access token e devolve também a sua expiração,
+// para que os clientes agendem a renovação
+func (m *Manager) GenerateAccessToken(user string, rooms []string) (string, time.Time, error) {
+	jti, err := newID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(m.AccessTTL)
 
-func GenerateAccessToken(user string, rooms []string) (string, error) {
 	claims := Claims{
 		User:  user,
 		Rooms: rooms,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTTL)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "chat-app",
+			Issuer:    m.issuer,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(accessSecret)
+	key := m.keys.signing()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
-func GenerateRefreshToken(user string) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   user,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTTL)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		Issuer:    "chat-app",
+// ValidateAccessToken verifica assinatura, expiração e a lista de revogação
+func (m *Manager) ValidateAccessToken(ctx context.Context, tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, m.accessKeyFunc, jwt.WithIssuer(m.issuer))
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(refreshSecret)
-}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 
-func ValidateAccessToken(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return accessSecret, nil
-	})
+	revoked, err := m.revocations.IsRevoked(ctx, claims)
 	if err != nil {
 		return nil, err
 	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if revoked {
+		return nil, ErrTokenRevoked
 	}
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
 
-func ValidateRefreshToken(tokenStr string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return refreshSecret, nil
-	})
-	if err != nil {
-		return "", err
+// accessKeyFunc escolhe a chave de validação pelo kid do header
+func (m *Manager) accessKeyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	var key *signingKey
+	if kid == "" {
+		// tokens emitidos antes do keyring não têm kid
+		key = m.keys.signing()
+	} else if k, ok := m.keys.lookup(kid); ok {
+		key = k
+	} else if m.reloadForUnknownKid() {
+		key, _ = m.keys.lookup(kid)
 	}
 
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		return claims.Subject, nil
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
 	}
-	return "", errors.New("invalid refresh token")
+	return key.verifyKey, nil
 }
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JWKSMaxAge é por quanto tempo outros serviços podem manter o JWKS em cache.
+// Uma chave nova só passa a assinar depois de publicada por esse tempo, para
+// que quem tem o JWKS em cache já a conheça.
+const JWKSMaxAge = 5 * time.Minute
+
+// keyring mantém a chave ativa (usada para assinar) e as chaves em
+// aposentadoria, que continuam válidas para verificar tokens já emitidos
+type keyring struct {
+	mu     sync.RWMutex
+	static *signingKey
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+func newKeyring(static *signingKey) *keyring {
+	return &keyring{
+		static: static,
+		active: static,
+		keys:   map[string]*signingKey{static.kid: static},
+	}
+}
+
+// replace troca as chaves carregadas do KeyStore; a chave da configuração
+// continua aceita para validação e volta a assinar se o keyring ficar vazio.
+// Assina a chave mais nova já publicada há JWKSMaxAge: logo depois de uma
+// rotação a anterior, em aposentadoria, continua assinando.
+func (k *keyring) replace(loaded []*signingKey) {
+	keys := map[string]*signingKey{k.static.kid: k.static}
+	active := k.static
+	publishedBefore := time.Now().Add(-JWKSMaxAge)
+	for _, key := range loaded {
+		keys[key.kid] = key
+		if key.createdAt.After(publishedBefore) {
+			continue
+		}
+		if active == k.static || key.createdAt.After(active.createdAt) {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.active = active
+}
+
+func (k *keyring) signing() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+func (k *keyring) lookup(kid string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWK é a representação pública de uma chave (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwks lista as chaves públicas; segredos HMAC nunca são expostos
+func (k *keyring) jwks() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	KeyActive   = "active"
+	KeyRetiring = "retiring"
+)
+
+// signingKey agrupa o método de assinatura e as chaves usadas para assinar e validar
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	status    string
+	createdAt time.Time
+	retiredAt time.Time
+}
+
+// loadSecret lê o segredo do arquivo quando informado (ex.: Docker/Kubernetes secrets)
+func loadSecret(value, file string) ([]byte, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("auth: reading secret file %s: %w", file, err)
+		}
+		value = strings.TrimSpace(string(b))
+	}
+	if value == "" {
+		return nil, errors.New("auth: secret not configured")
+	}
+	return []byte(value), nil
+}
+
+func newHMACKey(value, file string) (*signingKey, error) {
+	secret, err := loadSecret(value, file)
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// loadPrivateKey lê uma chave PEM RSA (RS256) ou Ed25519 (EdDSA)
+func loadPrivateKey(algorithm string, pemBytes []byte) (*signingKey, error) {
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: parsing RSA private key: %w", err)
+		}
+		return &signingKey{method: jwt.SigningMethodRS256, signKey: key, verifyKey: key.Public().(*rsa.PublicKey)}, nil
+	case jwt.SigningMethodEdDSA.Alg():
+		key, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: parsing Ed25519 private key: %w", err)
+		}
+		return &signingKey{method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.(crypto.Signer).Public().(ed25519.PublicKey)}, nil
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q", algorithm)
+	}
+}
+
+// newAccessKey monta a chave dos access tokens de acordo com o algoritmo configurado
+func newAccessKey(cfg Config) (*signingKey, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+
+	if algorithm == jwt.SigningMethodHS256.Alg() {
+		key, err := newHMACKey(cfg.AccessSecret, cfg.AccessSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		// o kid de um segredo HMAC não pode ser derivado dele, pois vai no header do token
+		key.kid = "hs256-config"
+		return key, nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("auth: %s requires a private key file", algorithm)
+	}
+	pemBytes, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading private key %s: %w", cfg.PrivateKeyFile, err)
+	}
+	key, err := loadPrivateKey(algorithm, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	if key.kid, err = thumbprint(key.verifyKey); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// generateKey cria uma nova chave do algoritmo informado, usada na rotação
+func generateKey(algorithm string) (*signingKey, error) {
+	var key *signingKey
+
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		key = &signingKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+	case jwt.SigningMethodRS256.Alg():
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key = &signingKey{method: jwt.SigningMethodRS256, signKey: priv, verifyKey: &priv.PublicKey}
+	case jwt.SigningMethodEdDSA.Alg():
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = &signingKey{method: jwt.SigningMethodEdDSA, signKey: priv, verifyKey: pub}
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q", algorithm)
+	}
+
+	kid := make([]byte, 12)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	key.kid = base64.RawURLEncoding.EncodeToString(kid)
+	key.status = KeyActive
+	key.createdAt = time.Now()
+	return key, nil
+}
+
+// thumbprint deriva um kid estável a partir da chave pública
+func thumbprint(pub any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// encodeKey serializa o material da chave para persistência no keyring
+func encodeKey(k *signingKey) (string, error) {
+	if secret, ok := k.signKey.([]byte); ok {
+		return base64.StdEncoding.EncodeToString(secret), nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.signKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// decodeKey reconstrói uma chave persistida no keyring
+func decodeKey(stored StoredKey) (*signingKey, error) {
+	var key *signingKey
+	if stored.Algorithm == jwt.SigningMethodHS256.Alg() {
+		secret, err := base64.StdEncoding.DecodeString(stored.Material)
+		if err != nil {
+			return nil, err
+		}
+		key = &signingKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+	} else {
+		var err error
+		if key, err = loadPrivateKey(stored.Algorithm, []byte(stored.Material)); err != nil {
+			return nil, err
+		}
+	}
+
+	key.kid = stored.Kid
+	key.status = stored.Status
+	key.createdAt = stored.CreatedAt
+	key.retiredAt = stored.RetiredAt
+	return key, nil
+}
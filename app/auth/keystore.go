@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyringKey     = "auth:keys"
+	keyringChannel = "auth:keys:rotated"
+)
+
+// StoredKey é a forma persistida de uma chave do keyring
+type StoredKey struct {
+	Kid       string    `json:"kid"`
+	Algorithm string    `json:"alg"`
+	Material  string    `json:"material"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	RetiredAt time.Time `json:"retired_at,omitempty"`
+}
+
+// Interface para o keyring compartilhado entre as instâncias
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]StoredKey, error)
+	SaveKey(ctx context.Context, key StoredKey) error
+	DeleteKey(ctx context.Context, kid string) error
+	NotifyRotation(ctx context.Context) error
+	SubscribeRotations(ctx context.Context, handler func())
+}
+
+// RedisKeyStore guarda o keyring em um hash do Redis (kid -> chave)
+type RedisKeyStore struct {
+	client *redis.Client
+}
+
+func NewRedisKeyStore(client *redis.Client) *RedisKeyStore {
+	return &RedisKeyStore{client: client}
+}
+
+func (s *RedisKeyStore) LoadKeys(ctx context.Context) ([]StoredKey, error) {
+	vals, err := s.client.HGetAll(ctx, keyringKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]StoredKey, 0, len(vals))
+	for _, val := range vals {
+		var key StoredKey
+		if err := json.Unmarshal([]byte(val), &key); err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *RedisKeyStore) SaveKey(ctx context.Context, key StoredKey) error {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, keyringKey, key.Kid, payload).Err()
+}
+
+func (s *RedisKeyStore) DeleteKey(ctx context.Context, kid string) error {
+	return s.client.HDel(ctx, keyringKey, kid).Err()
+}
+
+func (s *RedisKeyStore) NotifyRotation(ctx context.Context) error {
+	return s.client.Publish(ctx, keyringChannel, "rotated").Err()
+}
+
+func (s *RedisKeyStore) SubscribeRotations(ctx context.Context, handler func()) {
+	pubsub := s.client.Subscribe(ctx, keyringChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			handler()
+		}
+	}
+}
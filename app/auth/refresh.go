@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// RefreshClaims identifica o token (jti) e a família de rotação (fam)
+type RefreshClaims struct {
+	Family string `json:"fam"`
+	jwt.RegisteredClaims
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateRefreshToken abre uma nova família de refresh tokens (um login)
+func (m *Manager) GenerateRefreshToken(ctx context.Context, user string) (string, error) {
+	family, err := newID()
+	if err != nil {
+		return "", err
+	}
+	jti, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.sessions.CreateFamily(ctx, family, user, jti, m.RefreshTTL); err != nil {
+		return "", err
+	}
+	return m.signRefreshToken(user, family, jti)
+}
+
+// RotateRefreshToken troca um refresh token válido pelo próximo da família.
+// Apresentar um token já trocado revoga a família, pois indica que ele vazou.
+func (m *Manager) RotateRefreshToken(ctx context.Context, tokenStr string) (string, string, error) {
+	claims, err := m.ValidateRefreshToken(tokenStr)
+	if err != nil {
+		return "", "", err
+	}
+
+	next, err := newID()
+	if err != nil {
+		return "", "", err
+	}
+
+	result, err := m.sessions.RotateFamily(ctx, claims.Family, claims.Subject, claims.ID, next, m.RefreshTTL)
+	if err != nil {
+		return "", "", err
+	}
+	switch result {
+	case rotateReused:
+		return "", "", ErrRefreshTokenReused
+	case rotateUnknown:
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	refresh, err := m.signRefreshToken(claims.Subject, claims.Family, next)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.Subject, refresh, nil
+}
+
+// Logout encerra a família do refresh token informado
+func (m *Manager) Logout(ctx context.Context, tokenStr string) (string, error) {
+	claims, err := m.ValidateRefreshToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, m.sessions.RevokeFamily(ctx, claims.Family)
+}
+
+// LogoutAll encerra todas as famílias de refresh tokens do usuário e
+// revoga os access tokens já emitidos, derrubando os WebSockets abertos
+func (m *Manager) LogoutAll(ctx context.Context, user string) error {
+	if err := m.sessions.RevokeUser(ctx, user); err != nil {
+		return err
+	}
+	return m.RevokeUserTokens(ctx, user)
+}
+
+func (m *Manager) signRefreshToken(user, family, jti string) (string, error) {
+	claims := RefreshClaims{
+		Family: family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.RefreshTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    m.issuer,
+		},
+	}
+
+	token := jwt.NewWithClaims(m.refresh.method, claims)
+	return token.SignedString(m.refresh.signKey)
+}
+
+func (m *Manager) ValidateRefreshToken(tokenStr string) (*RefreshClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &RefreshClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return m.refresh.verifyKey, nil
+	}, jwt.WithValidMethods([]string{m.refresh.method.Alg()}), jwt.WithIssuer(m.issuer))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	claims, ok := token.Claims.(*RefreshClaims)
+	if !ok || !token.Valid || claims.ID == "" || claims.Family == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
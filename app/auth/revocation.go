@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const revocationChannel = "auth:revocations"
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Revocation descreve um token revogado (TokenID) ou todos os tokens de um
+// usuário emitidos antes de um instante (User + Before)
+type Revocation struct {
+	TokenID string    `json:"jti,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Before  time.Time `json:"before,omitempty"`
+}
+
+// Matches indica se a revogação atinge o access token descrito pelas claims
+func (r Revocation) Matches(claims *Claims) bool {
+	if r.TokenID != "" && r.TokenID == claims.ID {
+		return true
+	}
+	if r.User != "" && r.User == claims.User && claims.IssuedAt != nil {
+		return claims.IssuedAt.Time.Before(r.Before)
+	}
+	return false
+}
+
+// Interface para a lista de access tokens revogados
+type RevocationStore interface {
+	Revoke(ctx context.Context, revocation Revocation, ttl time.Duration) error
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+	SubscribeRevocations(ctx context.Context, handler func(Revocation))
+}
+
+// RedisRevocationStore guarda revoked:jti:<jti> e revoked:user:<user> com TTL
+// igual ao tempo que os tokens atingidos ainda seriam válidos
+type RedisRevocationStore struct {
+	client *redis.Client
+}
+
+func NewRedisRevocationStore(client *redis.Client) *RedisRevocationStore {
+	return &RedisRevocationStore{client: client}
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+func revokedUserKey(user string) string {
+	return "revoked:user:" + user
+}
+
+func (s *RedisRevocationStore) Revoke(ctx context.Context, r Revocation, ttl time.Duration) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	if r.TokenID != "" {
+		pipe.Set(ctx, revokedTokenKey(r.TokenID), 1, ttl)
+	}
+	if r.User != "" {
+		pipe.Set(ctx, revokedUserKey(r.User), r.Before.UnixMilli(), ttl)
+	}
+	pipe.Publish(ctx, revocationChannel, payload)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	pipe := s.client.Pipeline()
+	byToken := pipe.Exists(ctx, revokedTokenKey(claims.ID))
+	byUser := pipe.Get(ctx, revokedUserKey(claims.User))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	if claims.ID != "" && byToken.Val() > 0 {
+		return true, nil
+	}
+	if before, err := strconv.ParseInt(byUser.Val(), 10, 64); err == nil {
+		r := Revocation{User: claims.User, Before: time.UnixMilli(before)}
+		return r.Matches(claims), nil
+	}
+	return false, nil
+}
+
+func (s *RedisRevocationStore) SubscribeRevocations(ctx context.Context, handler func(Revocation)) {
+	pubsub := s.client.Subscribe(ctx, revocationChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var r Revocation
+			if err := json.Unmarshal([]byte(msg.Payload), &r); err != nil {
+				continue
+			}
+			handler(r)
+		}
+	}
+}
+
+// RevokeAccessToken revoga um access token até a sua expiração
+func (m *Manager) RevokeAccessToken(ctx context.Context, claims *Claims) error {
+	ttl := m.AccessTTL
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return m.revocations.Revoke(ctx, Revocation{TokenID: claims.ID}, ttl)
+}
+
+// RevokeUserTokens revoga todos os access tokens já emitidos para o usuário.
+// O iat tem precisão de segundos, então o corte é truncado para não revogar
+// um login feito logo em seguida, no mesmo segundo.
+func (m *Manager) RevokeUserTokens(ctx context.Context, user string) error {
+	before := time.Now().Truncate(time.Second)
+	return m.revocations.Revoke(ctx, Revocation{User: user, Before: before}, m.AccessTTL)
+}
+
+// SubscribeRevocations repassa as revogações feitas em qualquer instância
+func (m *Manager) SubscribeRevocations(ctx context.Context, handler func(Revocation)) {
+	m.revocations.SubscribeRevocations(ctx, handler)
+}
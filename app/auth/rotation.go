@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/logger"
+)
+
+const (
+	reloadInterval     = time.Minute
+	unknownKidCooldown = 5 * time.Second
+)
+
+// Reload carrega o keyring compartilhado do KeyStore
+func (m *Manager) Reload(ctx context.Context) error {
+	stored, err := m.store.LoadKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := make([]*signingKey, 0, len(stored))
+	for _, s := range stored {
+		key, err := decodeKey(s)
+		if err != nil {
+			continue
+		}
+		loaded = append(loaded, key)
+	}
+	m.keys.replace(loaded)
+
+	m.reloadMu.Lock()
+	m.lastReload = time.Now()
+	m.reloadMu.Unlock()
+	return nil
+}
+
+// reloadForUnknownKid recarrega o keyring quando chega um token assinado por
+// uma chave recém-rotacionada em outra instância, com limite de frequência
+func (m *Manager) reloadForUnknownKid() bool {
+	m.reloadMu.Lock()
+	recent := time.Since(m.lastReload) < unknownKidCooldown
+	m.reloadMu.Unlock()
+	if recent {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return m.Reload(ctx) == nil
+}
+
+// Rotate gera uma nova chave ativa, move a anterior para aposentadoria e
+// remove as chaves aposentadas há mais tempo que a validade dos access tokens.
+// A anterior ainda assina por JWKSMaxAge, enquanto a nova é publicada.
+func (m *Manager) Rotate(ctx context.Context) (string, error) {
+	stored, err := m.store.LoadKeys(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := generateKey(m.algorithm)
+	if err != nil {
+		return "", err
+	}
+	material, err := encodeKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.store.SaveKey(ctx, StoredKey{
+		Kid:       key.kid,
+		Algorithm: key.method.Alg(),
+		Material:  material,
+		Status:    KeyActive,
+		CreatedAt: key.createdAt,
+	}); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	for _, s := range stored {
+		switch {
+		case s.Status == KeyActive:
+			s.Status = KeyRetiring
+			s.RetiredAt = now
+			if err := m.store.SaveKey(ctx, s); err != nil {
+				return "", err
+			}
+		case now.Sub(s.RetiredAt) > JWKSMaxAge+reloadInterval+m.AccessTTL+time.Minute:
+			if err := m.store.DeleteKey(ctx, s.Kid); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := m.store.NotifyRotation(ctx); err != nil {
+		return "", err
+	}
+	return key.kid, m.Reload(ctx)
+}
+
+// Watch mantém o keyring atualizado: recarrega a cada aviso de rotação
+// e periodicamente, caso algum aviso tenha sido perdido
+func (m *Manager) Watch(ctx context.Context, logger logger.Logger) {
+	reload := func() {
+		if err := m.Reload(ctx); err != nil {
+			logger.ErrorF("Erro ao recarregar chaves de assinatura: %v", err)
+		}
+	}
+
+	go m.store.SubscribeRotations(ctx, reload)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
+
+// JWKS retorna as chaves públicas ativas e em aposentadoria
+func (m *Manager) JWKS() JWKS {
+	return m.keys.jwks()
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	rotateUnknown = -1
+	rotateReused  = 0
+	rotateOK      = 1
+)
+
+// Interface para as famílias de refresh tokens (uma família por login)
+type SessionStore interface {
+	CreateFamily(ctx context.Context, family, user, jti string, ttl time.Duration) error
+	RotateFamily(ctx context.Context, family, user, currentJTI, nextJTI string, ttl time.Duration) (int, error)
+	RevokeFamily(ctx context.Context, family string) error
+	RevokeUser(ctx context.Context, user string) error
+}
+
+// RedisSessionStore guarda em refresh:family:<id> o jti válido da família
+// e em refresh:user:<user> as famílias abertas do usuário
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+func familyKey(family string) string {
+	return "refresh:family:" + family
+}
+
+func userFamiliesKey(user string) string {
+	return "refresh:user:" + user
+}
+
+// rotateScript troca o jti da família apenas se o apresentado for o atual;
+// um jti antigo indica reuso e derruba a família inteira
+var rotateScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'jti')
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+redis.call('HSET', KEYS[1], 'jti', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+func (s *RedisSessionStore) CreateFamily(ctx context.Context, family, user, jti string, ttl time.Duration) error {
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, familyKey(family), "user", user, "jti", jti)
+	pipe.Expire(ctx, familyKey(family), ttl)
+	pipe.SAdd(ctx, userFamiliesKey(user), family)
+	pipe.Expire(ctx, userFamiliesKey(user), ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisSessionStore) RotateFamily(ctx context.Context, family, user, currentJTI, nextJTI string, ttl time.Duration) (int, error) {
+	keys := []string{familyKey(family), userFamiliesKey(user)}
+	return rotateScript.Run(ctx, s.client, keys, currentJTI, nextJTI, ttl.Milliseconds()).Int()
+}
+
+func (s *RedisSessionStore) RevokeFamily(ctx context.Context, family string) error {
+	user, err := s.client.HGet(ctx, familyKey(family), "user").Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, familyKey(family))
+	pipe.SRem(ctx, userFamiliesKey(user), family)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisSessionStore) RevokeUser(ctx context.Context, user string) error {
+	families, err := s.client.SMembers(ctx, userFamiliesKey(user)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(families)+1)
+	for _, family := range families {
+		keys = append(keys, familyKey(family))
+	}
+	keys = append(keys, userFamiliesKey(user))
+	return s.client.Del(ctx, keys...).Err()
+}
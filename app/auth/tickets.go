@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TicketTTL é a validade do ticket de conexão do WebSocket
+const TicketTTL = 30 * time.Second
+
+var ErrInvalidTicket = errors.New("invalid or expired ticket")
+
+// Interface para os tickets de conexão de uso único
+type TicketStore interface {
+	SaveTicket(ctx context.Context, ticket, accessToken string, ttl time.Duration) error
+	ConsumeTicket(ctx context.Context, ticket string) (string, error)
+}
+
+// RedisTicketStore guarda ws:ticket:<ticket> -> access token, visível a
+// todas as instâncias atrás do Nginx
+type RedisTicketStore struct {
+	client *redis.Client
+}
+
+func NewRedisTicketStore(client *redis.Client) *RedisTicketStore {
+	return &RedisTicketStore{client: client}
+}
+
+func ticketKey(ticket string) string {
+	return "ws:ticket:" + ticket
+}
+
+func (s *RedisTicketStore) SaveTicket(ctx context.Context, ticket, accessToken string, ttl time.Duration) error {
+	return s.client.Set(ctx, ticketKey(ticket), accessToken, ttl).Err()
+}
+
+// ConsumeTicket usa GETDEL para que o ticket só possa ser usado uma vez
+func (s *RedisTicketStore) ConsumeTicket(ctx context.Context, ticket string) (string, error) {
+	token, err := s.client.GetDel(ctx, ticketKey(ticket)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidTicket
+	}
+	return token, err
+}
+
+// IssueTicket troca um access token válido por um ticket de conexão
+func (m *Manager) IssueTicket(ctx context.Context, accessToken string) (string, time.Time, error) {
+	ticket, err := newID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := m.tickets.SaveTicket(ctx, ticket, accessToken, TicketTTL); err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, time.Now().Add(TicketTTL), nil
+}
+
+// RedeemTicket consome o ticket e revalida o access token associado, para
+// que revogações feitas depois da emissão do ticket também valham
+func (m *Manager) RedeemTicket(ctx context.Context, ticket string) (*Claims, error) {
+	if ticket == "" {
+		return nil, ErrInvalidTicket
+	}
+	token, err := m.tickets.ConsumeTicket(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	return m.ValidateAccessToken(ctx, token)
+}
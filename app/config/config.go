@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -36,6 +38,61 @@ func Read() (*Config, error) {
 	v.BindEnv("redis.dial_timeout", "REDIS_DIAL_TIMEOUT")
 	v.BindEnv("redis.read_timeout", "REDIS_READ_TIMEOUT")
 	v.BindEnv("redis.write_timeout", "REDIS_WRITE_TIMEOUT")
+	v.BindEnv("redis.transport", "REDIS_TRANSPORT")
+	v.BindEnv("redis.stream_max_len", "REDIS_STREAM_MAX_LEN")
+	v.BindEnv("redis.stream_instance", "REDIS_STREAM_INSTANCE")
+
+	v.SetDefault("redis.transport", "pubsub")
+	v.SetDefault("redis.stream_max_len", 10000)
+	if hostname, err := os.Hostname(); err == nil {
+		v.SetDefault("redis.stream_instance", hostname)
+	}
+
+	v.BindEnv("users.store", "USERS_STORE")
+	v.BindEnv("users.default_rooms", "USERS_DEFAULT_ROOMS")
+	v.BindEnv("users.seed", "USERS_SEED")
+	v.BindEnv("users.admins", "USERS_ADMINS")
+	v.BindEnv("users.reset_token_ttl", "USERS_RESET_TOKEN_TTL")
+
+	v.SetDefault("users.store", "redis")
+	v.SetDefault("users.default_rooms", []string{"default"})
+	v.SetDefault("users.reset_token_ttl", 30*time.Minute)
+
+	v.BindEnv("auth.algorithm", "AUTH_ALGORITHM")
+	v.BindEnv("auth.issuer", "AUTH_ISSUER")
+	v.BindEnv("auth.access_secret", "AUTH_ACCESS_SECRET")
+	v.BindEnv("auth.access_secret_file", "AUTH_ACCESS_SECRET_FILE")
+	v.BindEnv("auth.refresh_secret", "AUTH_REFRESH_SECRET")
+	v.BindEnv("auth.refresh_secret_file", "AUTH_REFRESH_SECRET_FILE")
+	v.BindEnv("auth.private_key_file", "AUTH_PRIVATE_KEY_FILE")
+	v.BindEnv("auth.access_ttl", "AUTH_ACCESS_TTL")
+	v.BindEnv("auth.refresh_ttl", "AUTH_REFRESH_TTL")
+
+	v.SetDefault("auth.algorithm", "HS256")
+	v.SetDefault("auth.issuer", "chat-app")
+	v.SetDefault("auth.access_ttl", 5*time.Minute)
+	v.SetDefault("auth.refresh_ttl", 24*time.Hour)
+
+	v.BindEnv("websocket.allowed_origins", "WEBSOCKET_ALLOWED_ORIGINS")
+	v.BindEnv("websocket.ping_interval", "WEBSOCKET_PING_INTERVAL")
+	v.BindEnv("websocket.pong_wait", "WEBSOCKET_PONG_WAIT")
+	v.BindEnv("websocket.write_wait", "WEBSOCKET_WRITE_WAIT")
+	v.BindEnv("websocket.max_message_size", "WEBSOCKET_MAX_MESSAGE_SIZE")
+
+	v.SetDefault("websocket.ping_interval", 50*time.Second)
+	v.SetDefault("websocket.pong_wait", 60*time.Second)
+	v.SetDefault("websocket.write_wait", 10*time.Second)
+	v.SetDefault("websocket.max_message_size", 32*1024)
+
+	v.BindEnv("history.max_messages", "HISTORY_MAX_MESSAGES")
+	v.BindEnv("history.max_age", "HISTORY_MAX_AGE")
+	v.BindEnv("history.unread_ttl", "HISTORY_UNREAD_TTL")
+	v.BindEnv("history.sweep_interval", "HISTORY_SWEEP_INTERVAL")
+
+	v.SetDefault("history.max_messages", 50)
+	v.SetDefault("history.max_age", 6*time.Hour)
+	v.SetDefault("history.unread_ttl", 24*time.Hour)
+	v.SetDefault("history.sweep_interval", time.Minute)
 
 	v.BindEnv("app_name", "APP_NAME")
 	v.BindEnv("env", "ENV")
@@ -3,10 +3,14 @@ package config
 import "time"
 
 type Config struct {
-	Server  ServerConfig `mapstructure:"server"`
-	Redis   RedisConfig  `mapstructure:"redis"`
-	AppName string       `mapstructure:"app_name"`
-	Env     string       `mapstructure:"env"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Users     UsersConfig     `mapstructure:"users"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	WebSocket WebSocketConfig `mapstructure:"websocket"`
+	History   HistoryConfig   `mapstructure:"history"`
+	AppName   string          `mapstructure:"app_name"`
+	Env       string          `mapstructure:"env"`
 }
 
 type ServerConfig struct {
@@ -15,12 +19,51 @@ type ServerConfig struct {
 }
 
 type RedisConfig struct {
-	Addr         string        `mapstructure:"addr"`
-	Password     string        `mapstructure:"password"`
-	DB           int           `mapstructure:"db"`
-	PoolSize     int           `mapstructure:"pool_size"`
-	MinIdleConns int           `mapstructure:"min_idle_conns"`
-	DialTimeout  time.Duration `mapstructure:"dial_timeout"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	Addr           string        `mapstructure:"addr"`
+	Password       string        `mapstructure:"password"`
+	DB             int           `mapstructure:"db"`
+	PoolSize       int           `mapstructure:"pool_size"`
+	MinIdleConns   int           `mapstructure:"min_idle_conns"`
+	DialTimeout    time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout    time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout   time.Duration `mapstructure:"write_timeout"`
+	Transport      string        `mapstructure:"transport"`
+	StreamMaxLen   int64         `mapstructure:"stream_max_len"`
+	StreamInstance string        `mapstructure:"stream_instance"`
+}
+
+type UsersConfig struct {
+	Store         string        `mapstructure:"store"`
+	DefaultRooms  []string      `mapstructure:"default_rooms"`
+	Seed          []string      `mapstructure:"seed"`
+	Admins        []string      `mapstructure:"admins"`
+	ResetTokenTTL time.Duration `mapstructure:"reset_token_ttl"`
+}
+
+type AuthConfig struct {
+	Algorithm         string        `mapstructure:"algorithm"`
+	Issuer            string        `mapstructure:"issuer"`
+	AccessSecret      string        `mapstructure:"access_secret"`
+	AccessSecretFile  string        `mapstructure:"access_secret_file"`
+	RefreshSecret     string        `mapstructure:"refresh_secret"`
+	RefreshSecretFile string        `mapstructure:"refresh_secret_file"`
+	PrivateKeyFile    string        `mapstructure:"private_key_file"`
+	AccessTTL         time.Duration `mapstructure:"access_ttl"`
+	RefreshTTL        time.Duration `mapstructure:"refresh_ttl"`
+}
+
+type WebSocketConfig struct {
+	AllowedOrigins []string      `mapstructure:"allowed_origins"`
+	PingInterval   time.Duration `mapstructure:"ping_interval"`
+	PongWait       time.Duration `mapstructure:"pong_wait"`
+	WriteWait      time.Duration `mapstructure:"write_wait"`
+	MaxMessageSize int64         `mapstructure:"max_message_size"`
+}
+
+// HistoryConfig é a retenção padrão das salas sem política própria
+type HistoryConfig struct {
+	MaxMessages   int           `mapstructure:"max_messages"`
+	MaxAge        time.Duration `mapstructure:"max_age"`
+	UnreadTTL     time.Duration `mapstructure:"unread_ttl"`
+	SweepInterval time.Duration `mapstructure:"sweep_interval"`
 }
@@ -1,6 +1,28 @@
 package dto
 
+import "time"
+
 type Auth struct {
 	User     string `json:"user"`
 	Password string `json:"password"`
 }
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PasswordReset struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// TokenResponse é devolvido no login e no refresh; expires_at/expires_in e
+// rooms permitem ao cliente agendar a renovação e saber quais salas recebeu
+type TokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	ExpiresIn    int       `json:"expires_in"`
+	Rooms        []string  `json:"rooms"`
+}
@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion é a versão do envelope trocado no WebSocket
+const ProtocolVersion = 1
+
+type EventType string
+
+const (
+	EventMessage  EventType = "message"
+	EventSystem   EventType = "system"
+	EventError    EventType = "error"
+	EventAck      EventType = "ack"
+	EventPresence EventType = "presence"
+	EventHistory  EventType = "history"
+	EventResume   EventType = "resume"
+	EventJoin     EventType = "join"
+	EventLeave    EventType = "leave"
+
+	EventTypingStart EventType = "typing.start"
+	EventTypingStop  EventType = "typing.stop"
+	EventRead        EventType = "read"
+	EventEdit        EventType = "edit"
+	EventDelete      EventType = "delete"
+	EventReact       EventType = "react"
+	EventUnreact     EventType = "unreact"
+	EventThreadReply EventType = "thread.reply"
+)
+
+// Envelope é o formato de todos os frames do WebSocket, nos dois sentidos.
+// No frame do cliente, id é opcional e volta nas respostas (ack/error)
+// para que o cliente relacione a resposta ao pedido.
+type Envelope struct {
+	Version int             `json:"v"`
+	Type    EventType       `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Room    string          `json:"room,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SystemEvent é o payload dos eventos do tipo system
+type SystemEvent struct {
+	Event   string `json:"event"`
+	Message string `json:"message,omitempty"`
+	Role    string `json:"role,omitempty"`
+}
+
+// ErrorEvent é o payload dos eventos do tipo error
+type ErrorEvent struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+}
+
+// AckEvent confirma que a mensagem foi gravada e publicada, com o ID
+// atribuído pelo servidor
+type AckEvent struct {
+	ClientMsgID string    `json:"client_msg_id,omitempty"`
+	MessageID   string    `json:"message_id"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// HistoryEvent agrupa mensagens já entregues antes da conexão; Unread indica
+// o replay das mensagens privadas recebidas enquanto o usuário estava offline,
+// Gap que parte das mensagens pedidas já saiu do histórico e HasMore que há
+// mensagens mais antigas que a página enviada
+type HistoryEvent struct {
+	Messages []Message `json:"messages"`
+	Unread   bool      `json:"unread,omitempty"`
+	Gap      bool      `json:"gap,omitempty"`
+	HasMore  bool      `json:"has_more,omitempty"`
+}
+
+// HistoryRequest é o payload do frame history enviado pelo cliente para
+// paginar o histórico para trás
+type HistoryRequest struct {
+	Before string `json:"before,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ResumeRequest é o payload do frame resume: o último ID que o cliente já tem
+type ResumeRequest struct {
+	Since string `json:"since"`
+}
+
+// JoinRequest é o payload opcional do frame join
+type JoinRequest struct {
+	Since string `json:"since,omitempty"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// RoomEvent é um evento da sala que não é uma mensagem nova (digitação,
+// presença, recibos, edições, reações, respostas em thread), distribuído entre
+// as instâncias pelo canal events:<sala>. O evento em si nunca é gravado; o
+// que altera o histórico é gravado antes da publicação.
+type RoomEvent struct {
+	Type       EventType `json:"type"`
+	RoomID     string    `json:"room_id"`
+	User       string    `json:"user"`
+	Status     string    `json:"status,omitempty"`
+	MessageID  string    `json:"message_id,omitempty"`
+	Message    *Message  `json:"message,omitempty"`
+	Emoji      string    `json:"emoji,omitempty"`
+	ReplyCount int64     `json:"reply_count,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+}
+
+// TypingEvent é o payload dos eventos typing.start e typing.stop
+type TypingEvent struct {
+	User      string     `json:"user"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// PresenceEvent é o payload dos eventos presence: Users traz a lista de quem
+// está na sala ao entrar nela, e User/Status cada entrada ou saída depois disso
+type PresenceEvent struct {
+	Users  []string `json:"users,omitempty"`
+	User   string   `json:"user,omitempty"`
+	Status string   `json:"status,omitempty"`
+}
+
+// ReadEvent é o payload do frame read: o cliente informa a última mensagem
+// lida e a sala recebe o recibo com o usuário
+type ReadEvent struct {
+	User      string `json:"user,omitempty"`
+	MessageID string `json:"message_id"`
+}
+
+// ThreadReplyEvent é o payload do evento thread.reply, enviado à sala a cada
+// nova resposta em uma thread
+type ThreadReplyEvent struct {
+	ParentID   string  `json:"parent_id"`
+	ReplyCount int64   `json:"reply_count"`
+	Reply      Message `json:"reply"`
+}
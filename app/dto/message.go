@@ -2,15 +2,53 @@ package dto
 
 import "time"
 
+// Message apagada continua no histórico como lápide (Deleted), sem o conteúdo
 type Message struct {
-	User      string    `json:"user"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
-	RoomID    string    `json:"room_id,omitempty"`
-	Target    string    `json:"target,omitempty"`
+	ID          string     `json:"id"`
+	ClientMsgID string     `json:"client_msg_id,omitempty"`
+	User        string     `json:"user"`
+	Content     string     `json:"content"`
+	Timestamp   time.Time  `json:"timestamp"`
+	RoomID      string     `json:"room_id,omitempty"`
+	Target      string     `json:"target,omitempty"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	Deleted     bool       `json:"deleted,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy   string     `json:"deleted_by,omitempty"`
+	Reactions   []Reaction `json:"reactions,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	ReplyCount  int64      `json:"reply_count,omitempty"`
+}
+
+// VisibleTo indica se user pode ver a mensagem: as privadas só aparecem para
+// o remetente e o destinatário
+func (m Message) VisibleTo(user string) bool {
+	return m.Target == "" || m.User == user || m.Target == user
+}
+
+// Reaction agrega as reações de uma mensagem com o mesmo emoji
+type Reaction struct {
+	Emoji string   `json:"emoji"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
 }
 
 type Incoming struct {
-	Content string `json:"content"`
-	Target  string `json:"target"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+	Content     string `json:"content"`
+	Target      string `json:"target"`
+	ReplyTo     string `json:"reply_to,omitempty"`
+}
+
+// MessageChange é o payload dos frames edit e delete enviados pelo cliente
+type MessageChange struct {
+	MessageID string `json:"message_id"`
+	Content   string `json:"content,omitempty"`
+}
+
+// ReactionChange é o payload dos frames react e unreact, nos dois sentidos
+type ReactionChange struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+	User      string `json:"user,omitempty"`
 }
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+// Room sem Retention segue a retenção padrão da configuração
+type Room struct {
+	ID          string     `json:"id"`
+	Topic       string     `json:"topic"`
+	DefaultRole string     `json:"default_role"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Retention   *Retention `json:"retention,omitempty"`
+}
+
+type CreateRoom struct {
+	ID           string     `json:"id"`
+	Topic        string     `json:"topic"`
+	Announcement bool       `json:"announcement"`
+	Retention    *Retention `json:"retention,omitempty"`
+}
+
+// Retention é a política de histórico de uma sala: MaxMessages 0 não limita a
+// quantidade e MaxAge vazio não limita a idade (duração Go, ex.: "2160h")
+type Retention struct {
+	MaxMessages int    `json:"max_messages"`
+	MaxAge      string `json:"max_age,omitempty"`
+}
+
+type RoomTopic struct {
+	Topic string `json:"topic"`
+}
+
+type RoomRole struct {
+	Role string `json:"role"`
+}
+
+// Kick avisa todas as instâncias para desconectar o usuário da sala
+type Kick struct {
+	RoomID string `json:"room_id"`
+	User   string `json:"user"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Rooms        []string  `json:"rooms"`
+	Admin        bool      `json:"admin"`
+	CreatedAt    time.Time `json:"created_at"`
+}
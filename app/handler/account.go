@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/auth"
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/user"
+	"github.com/labstack/echo/v4"
+)
+
+func Register(users user.UserStore, defaultRooms []string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var cred dto.Auth
+
+		if err := c.Bind(&cred); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+
+		if err := user.ValidateUsername(cred.User); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		if err := user.ValidatePassword(cred.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+
+		hash, err := user.HashPassword(cred.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create user"})
+		}
+
+		err = users.CreateUser(c.Request().Context(), dto.User{
+			Username:     cred.User,
+			PasswordHash: hash,
+			Rooms:        defaultRooms,
+		})
+		if errors.Is(err, user.ErrAlreadyExists) {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "user already exists"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create user"})
+		}
+
+		return c.JSON(http.StatusCreated, echo.Map{
+			"user":  cred.User,
+			"rooms": defaultRooms,
+		})
+	}
+}
+
+func ChangePassword(users user.UserStore, tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := claimsFrom(c)
+		if claims == nil {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+		}
+
+		var req dto.PasswordChange
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+
+		ctx := c.Request().Context()
+		_, err := users.VerifyPassword(ctx, claims.User, req.CurrentPassword)
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid credentials"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not verify credentials"})
+		}
+
+		return updatePassword(c, users, tokens, claims.User, req.NewPassword)
+	}
+}
+
+// RequestPasswordReset permite que um administrador gere um token de
+// redefinição de uso único, entregue ao usuário por fora do chat
+func RequestPasswordReset(users user.UserStore, ttl time.Duration) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isAdmin(c, users) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		ctx := c.Request().Context()
+		target := c.Param("user")
+		if _, err := users.GetUser(ctx, target); errors.Is(err, user.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "user not found"})
+		} else if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load user"})
+		}
+
+		token, err := user.NewResetToken()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not generate reset token"})
+		}
+		if err := users.SaveResetToken(ctx, token, target, ttl); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not generate reset token"})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"user":        target,
+			"reset_token": token,
+			"expires_at":  time.Now().Add(ttl),
+		})
+	}
+}
+
+func ResetPassword(users user.UserStore, tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req dto.PasswordReset
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+
+		// valida antes de consumir o token para que uma senha fraca não o invalide
+		if err := user.ValidatePassword(req.NewPassword); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+
+		name, err := users.ConsumeResetToken(c.Request().Context(), req.Token)
+		if errors.Is(err, user.ErrInvalidResetToken) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not reset password"})
+		}
+
+		return updatePassword(c, users, tokens, name, req.NewPassword)
+	}
+}
+
+// updatePassword grava a nova senha e encerra as sessões abertas com a senha antiga
+func updatePassword(c echo.Context, users user.UserStore, tokens *auth.Manager, name, password string) error {
+	if err := user.ValidatePassword(password); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	hash, err := user.HashPassword(password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update password"})
+	}
+
+	ctx := c.Request().Context()
+	if err := users.UpdatePassword(ctx, name, hash); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update password"})
+	}
+	if err := tokens.LogoutAll(ctx, name); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not revoke sessions"})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
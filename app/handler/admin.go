@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/brunobotter/chat-websocket/room"
+	"github.com/brunobotter/chat-websocket/user"
+	"github.com/labstack/echo/v4"
+)
+
+// GrantRoom concede uma sala ao usuário; vale a partir do próximo refresh
+func GrantRoom(users user.UserStore, rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isAdmin(c, users) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		ctx := c.Request().Context()
+		if err := users.GrantRoom(ctx, c.Param("user"), c.Param("room")); err != nil {
+			return roomGrantResponse(c, err)
+		}
+		err := rooms.Readmit(ctx, c.Param("room"), c.Param("user"))
+		return roomGrantResponse(c, err)
+	}
+}
+
+// RemoveRoom retira a sala do usuário. O papel explícito é removido e o
+// usuário marcado como retirado, senão o papel ou o token atual manteriam o
+// acesso até o próximo refresh.
+func RemoveRoom(users user.UserStore, rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isAdmin(c, users) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		ctx := c.Request().Context()
+		if err := users.RemoveRoom(ctx, c.Param("user"), c.Param("room")); err != nil {
+			return roomGrantResponse(c, err)
+		}
+		err := rooms.RemoveMember(ctx, c.Param("room"), c.Param("user"))
+		return roomGrantResponse(c, err)
+	}
+}
+
+func roomGrantResponse(c echo.Context, err error) error {
+	if errors.Is(err, user.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "user not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update rooms"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// isAdmin consulta a conta do dono do token, para que a permissão
+// de administrador retirada valha imediatamente
+func isAdmin(c echo.Context, users user.UserStore) bool {
+	claims := claimsFrom(c)
+	if claims == nil {
+		return false
+	}
+	account, err := users.GetUser(c.Request().Context(), claims.User)
+	return err == nil && account.Admin
+}
@@ -1,71 +1,201 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/user"
+	"github.com/brunobotter/chat-websocket/websocket"
 	"github.com/labstack/echo/v4"
 )
 
-func Login(c echo.Context) error {
+func Login(users user.UserStore, tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var cred dto.Auth
+
+		if err := c.Bind(&cred); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
 
-	var cred dto.Auth
+		name := cred.User
+		pass := cred.Password
 
-	if err := c.Bind(&cred); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
-	}
+		if name == "" || pass == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing user or password"})
+		}
 
-	user := cred.User
-	pass := cred.Password
+		account, err := users.VerifyPassword(c.Request().Context(), name, pass)
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid credentials"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not verify credentials"})
+		}
 
-	if user == "" || pass == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing user or password"})
+		refresh, err := tokens.GenerateRefreshToken(c.Request().Context(), account.Username)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not generate token"})
+		}
+
+		return respondWithTokens(c, tokens, account.Username, account.Rooms, refresh)
 	}
+}
+
+func Refresh(users user.UserStore, tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		name, refresh, err := tokens.RotateRefreshToken(ctx, bearerToken(c))
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "refresh token reused, session revoked"})
+		}
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid refresh token"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not refresh session"})
+		}
+
+		// as salas são lidas de novo a cada refresh, então concessões e
+		// remoções valem a partir da próxima renovação do access token
+		rooms, err := users.AllowedRooms(ctx, name)
+		if errors.Is(err, user.ErrNotFound) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid refresh token"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load user"})
+		}
 
-	if pass != "1234" {
-		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid credentials"})
+		return respondWithTokens(c, tokens, name, rooms, refresh)
 	}
+}
 
-	rooms := []string{"default", "vip"}
-	access, _ := auth.GenerateAccessToken(user, rooms)
-	refresh, _ := auth.GenerateRefreshToken(user)
+func respondWithTokens(c echo.Context, tokens *auth.Manager, name string, rooms []string, refresh string) error {
+	access, expiresAt, err := tokens.GenerateAccessToken(name, rooms)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not generate token"})
+	}
 
-	return c.JSON(http.StatusOK, echo.Map{
-		"access_token":  access,
-		"refresh_token": refresh,
+	return c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresAt:    expiresAt,
+		ExpiresIn:    int(tokens.AccessTTL.Seconds()),
+		Rooms:        rooms,
 	})
 }
 
-func Refresh(c echo.Context) error {
-	authHeader := c.Request().Header.Get("Authorization")
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-
-	user, err := auth.ValidateRefreshToken(token)
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid refresh token"})
+// Logout encerra a sessão do refresh token enviado no header Authorization
+func Logout(tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		_, err := tokens.Logout(c.Request().Context(), bearerToken(c))
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid refresh token"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not logout"})
+		}
+		return c.NoContent(http.StatusNoContent)
 	}
+}
 
-	rooms := []string{"default", "vip"}
-	newAccess, _ := auth.GenerateAccessToken(user, rooms)
+// LogoutAll encerra todas as sessões do dono do refresh token
+func LogoutAll(tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
 
-	return c.JSON(http.StatusOK, echo.Map{"access_token": newAccess})
+		claims, err := tokens.ValidateRefreshToken(bearerToken(c))
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid refresh token"})
+		}
+		if err := tokens.LogoutAll(ctx, claims.Subject); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not logout"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
 }
 
-func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+// RevokeToken revoga o access token usado na requisição e derruba os
+// WebSockets abertos com ele em todas as instâncias
+func RevokeToken(tokens *auth.Manager) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
+		claims := claimsFrom(c)
+		if claims == nil {
 			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
 		}
+		if err := tokens.RevokeAccessToken(c.Request().Context(), claims); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not revoke token"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		_, err := auth.ValidateAccessToken(token)
+// IssueTicket troca o access token por um ticket de uso único para
+// conectar ao WebSocket com ?ticket=, válido por 30 segundos
+func IssueTicket(tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ticket, expiresAt, err := tokens.IssueTicket(c.Request().Context(), bearerToken(c))
 		if err != nil {
-			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid token"})
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not issue ticket"})
 		}
+		return c.JSON(http.StatusOK, echo.Map{
+			"ticket":     ticket,
+			"expires_at": expiresAt,
+		})
+	}
+}
 
-		return next(c)
+func bearerToken(c echo.Context) string {
+	return strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+}
+
+func JWTMiddleware(tokens *auth.Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := authenticate(c, tokens)
+			if errors.Is(err, errMissingToken) {
+				return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid token"})
+			}
+
+			c.Set(claimsContextKey, claims)
+			return next(c)
+		}
+	}
+}
+
+var errMissingToken = errors.New("missing token")
+
+// authenticate lê o access token do header Authorization. Em pedidos de
+// upgrade para WebSocket também aceita o subprotocolo ("bearer", <token>)
+// e o ticket de uso único (?ticket=), já que navegadores não enviam o header.
+func authenticate(c echo.Context, tokens *auth.Manager) (*auth.Claims, error) {
+	r := c.Request()
+	if header := r.Header.Get("Authorization"); header != "" {
+		return tokens.ValidateAccessToken(r.Context(), strings.TrimPrefix(header, "Bearer "))
+	}
+
+	if websocket.IsUpgradeRequest(r) {
+		if token := websocket.SubprotocolToken(r); token != "" {
+			return tokens.ValidateAccessToken(r.Context(), token)
+		}
+		if ticket := c.QueryParam("ticket"); ticket != "" {
+			return tokens.RedeemTicket(r.Context(), ticket)
+		}
 	}
+
+	return nil, errMissingToken
+}
+
+const claimsContextKey = "claims"
+
+// claimsFrom retorna as claims gravadas pelo JWTMiddleware
+func claimsFrom(c echo.Context) *auth.Claims {
+	claims, _ := c.Get(claimsContextKey).(*auth.Claims)
+	return claims
 }
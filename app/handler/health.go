@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/brunobotter/chat-websocket/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// Health responde que a instância está no ar, com o total de conexões
+// WebSocket recusadas pela origem desde que ela subiu
+func Health(hub *websocket.Hub) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{
+			"status":           "ok",
+			"rejected_origins": hub.RejectedOrigins(),
+		})
+	}
+}
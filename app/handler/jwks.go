@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/brunobotter/chat-websocket/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS publica as chaves públicas para que outros serviços validem os access tokens
+func JWKS(tokens *auth.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(auth.JWKSMaxAge.Seconds())))
+		return c.JSON(http.StatusOK, tokens.JWKS())
+	}
+}
@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/presence"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+	"github.com/brunobotter/chat-websocket/user"
+	"github.com/labstack/echo/v4"
+)
+
+var roomIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// CreateRoom cria a sala com o autor como owner. Salas de avisos têm
+// readonly como papel padrão: só owner e moderadores enviam mensagens.
+func CreateRoom(rooms room.Store, users user.UserStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := claimsFrom(c)
+		if claims == nil {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+		}
+
+		var req dto.CreateRoom
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+		if !roomIDPattern.MatchString(req.ID) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid room id"})
+		}
+		if req.Retention != nil {
+			if _, err := redis.ParseRetention(*req.Retention); err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid retention"})
+			}
+		}
+
+		defaultRole := room.RoleMember
+		if req.Announcement {
+			defaultRole = room.RoleReadOnly
+		}
+
+		ctx := c.Request().Context()
+		// uma sala antiga sem registro já concedida a alguém não pode ser tomada
+		granted, err := users.RoomGranted(ctx, req.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create room"})
+		}
+		if granted {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "room already exists"})
+		}
+
+		r := dto.Room{
+			ID:          req.ID,
+			Topic:       req.Topic,
+			DefaultRole: string(defaultRole),
+			CreatedBy:   claims.User,
+			CreatedAt:   time.Now(),
+			Retention:   req.Retention,
+		}
+		err = rooms.CreateRoom(ctx, r)
+		if errors.Is(err, room.ErrAlreadyExists) {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "room already exists"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create room"})
+		}
+
+		if err := rooms.SetRole(ctx, r.ID, claims.User, room.RoleOwner); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create room"})
+		}
+		if err := users.GrantRoom(ctx, claims.User, r.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not create room"})
+		}
+
+		return c.JSON(http.StatusCreated, r)
+	}
+}
+
+func GetRoom(rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+
+		ctx := c.Request().Context()
+		r, err := rooms.GetRoom(ctx, roomID)
+		if errors.Is(err, room.ErrNotFound) {
+			r = &dto.Room{ID: roomID, DefaultRole: string(room.RoleMember)}
+		} else if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load room"})
+		}
+
+		members, err := rooms.Members(ctx, roomID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load room"})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"room":    r,
+			"role":    role,
+			"members": members,
+		})
+	}
+}
+
+func UpdateTopic(rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermChangeTopic) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		var req dto.RoomTopic
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+		if err := rooms.SetTopic(c.Request().Context(), roomID, req.Topic); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update topic"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// UpdateRetention define a política de histórico da sala, aplicada nas
+// próximas gravações e pelo sweeper
+func UpdateRetention(rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermManageRetention) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		var req dto.Retention
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+		if _, err := redis.ParseRetention(req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid retention"})
+		}
+		if err := rooms.SetRetention(c.Request().Context(), roomID, &req); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update retention"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// ResetRetention volta a sala para a retenção padrão da configuração
+func ResetRetention(rooms room.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermManageRetention) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		if err := rooms.SetRetention(c.Request().Context(), roomID, nil); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update retention"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// InviteMember concede a sala ao usuário, que entra com o papel padrão da sala
+func InviteMember(rooms room.Store, users user.UserStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermInvite) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		ctx := c.Request().Context()
+		target := c.Param("user")
+		if err := users.GrantRoom(ctx, target, roomID); err != nil {
+			return roomGrantResponse(c, err)
+		}
+		err := rooms.Readmit(ctx, roomID, target)
+		return roomGrantResponse(c, err)
+	}
+}
+
+// KickMember retira o usuário da sala e o desconecta dela em todas as instâncias
+func KickMember(rooms room.Store, users user.UserStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermKick) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		ctx := c.Request().Context()
+		target := c.Param("user")
+		targetRole, err := targetRoleOf(c, rooms, users, roomID, target)
+		if err != nil {
+			return roomGrantResponse(c, err)
+		}
+		if !role.Outranks(targetRole) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "cannot kick a member with an equal or higher role"})
+		}
+
+		if err := users.RemoveRoom(ctx, target, roomID); err != nil {
+			return roomGrantResponse(c, err)
+		}
+		if err := rooms.RemoveMember(ctx, roomID, target); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not kick member"})
+		}
+		if err := rooms.PublishKick(ctx, dto.Kick{RoomID: roomID, User: target}); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not kick member"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// SetMemberRole define o papel explícito do usuário na sala (apenas owner)
+func SetMemberRole(rooms room.Store, users user.UserStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		role := roleFrom(c)
+		if !role.Can(room.PermManageRoles) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		var req dto.RoomRole
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		}
+		newRole := room.Role(req.Role)
+		if !newRole.Valid() {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid role"})
+		}
+
+		ctx := c.Request().Context()
+		target := c.Param("user")
+		targetRole, err := targetRoleOf(c, rooms, users, roomID, target)
+		if err != nil {
+			return roomGrantResponse(c, err)
+		}
+		// ninguém concede um papel igual ou acima do seu nem altera quem está nesse nível
+		if !role.CanAssign(targetRole, newRole) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "cannot change the role of a member with an equal or higher role"})
+		}
+
+		if err := users.GrantRoom(ctx, target, roomID); err != nil {
+			return roomGrantResponse(c, err)
+		}
+		if err := rooms.SetRole(ctx, roomID, target, newRole); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not update role"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+const (
+	roomContextKey = "room"
+	roleContextKey = "role"
+)
+
+// RoomPresence lista quem está conectado à sala em qualquer instância
+func RoomPresence(online presence.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		users, err := online.Online(c.Request().Context(), roomID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load presence"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{
+			"room":  roomID,
+			"users": users,
+		})
+	}
+}
+
+// ReadReceipts retorna a última mensagem lida por cada usuário da sala, para
+// montar as listas de "visto por"
+func ReadReceipts(messages redis.MessageStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		cursors, err := messages.ReadCursors(c.Request().Context(), roomID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load read receipts"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{
+			"room":    roomID,
+			"cursors": cursors,
+		})
+	}
+}
+
+// UnreadCounts retorna quantas mensagens não lidas o usuário tem em cada sala do token
+func UnreadCounts(messages redis.MessageStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := claimsFrom(c)
+		if claims == nil {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+		}
+		counts, err := messages.UnreadCounts(c.Request().Context(), claims.User, claims.Rooms)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load unread counts"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{"rooms": counts})
+	}
+}
+
+// Paginação do histórico e das threads: padrão e máximo de mensagens por página
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// pageLimit lê o ?limit=, limitado a maxPageSize
+func pageLimit(c echo.Context) (int, bool) {
+	raw := c.QueryParam("limit")
+	if raw == "" {
+		return defaultPageSize, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return min(n, maxPageSize), true
+}
+
+// GetMessages pagina o histórico da sala, das mensagens mais recentes para as
+// mais antigas com ?before=<id>&limit=
+func GetMessages(messages redis.MessageStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := claimsFrom(c)
+		if claims == nil {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+		}
+		roomID := roomFrom(c)
+		if !roleFrom(c).Can(room.PermReadHistory) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		limit, ok := pageLimit(c)
+		if !ok {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+
+		// mensagens privadas só aparecem para o remetente e o destinatário
+		history, more, err := messages.GetMessagesBefore(c.Request().Context(), roomID, c.QueryParam("before"), limit, claims.User)
+		if errors.Is(err, redis.ErrInvalidMessageID) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid before"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load messages"})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"room":     roomID,
+			"messages": history,
+			"has_more": more,
+		})
+	}
+}
+
+// GetThread pagina as respostas de uma mensagem, das mais recentes para as
+// mais antigas com ?before=<id da resposta>&limit=
+func GetThread(messages redis.MessageStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		roomID := roomFrom(c)
+		if !roleFrom(c).Can(room.PermReadHistory) {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		limit, ok := pageLimit(c)
+		if !ok {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+
+		ctx := c.Request().Context()
+		parent, err := messages.GetMessage(ctx, roomID, c.Param("msgId"))
+		if errors.Is(err, redis.ErrMessageNotFound) || (err == nil && (parent.ParentID != "" || parent.Target != "")) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "message not found"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load thread"})
+		}
+
+		replies, more, err := messages.GetThread(ctx, roomID, parent.ID, c.QueryParam("before"), limit)
+		if errors.Is(err, redis.ErrInvalidMessageID) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid before"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load thread"})
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{
+			"room":     roomID,
+			"parent":   parent,
+			"replies":  replies,
+			"has_more": more,
+		})
+	}
+}
+
+// RoomAccess resolve o papel do dono do token na sala (parâmetro :id ou
+// ?room=) e responde 403 quando ele não tem acesso. Deve vir depois do JWTMiddleware.
+func RoomAccess(rooms room.Store) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := claimsFrom(c)
+			if claims == nil {
+				return c.JSON(http.StatusUnauthorized, echo.Map{"error": "missing token"})
+			}
+
+			roomID := c.Param("id")
+			if roomID == "" {
+				roomID = c.QueryParam("room")
+			}
+			if roomID == "" {
+				roomID = "default"
+			}
+
+			role, err := rooms.RoleOf(c.Request().Context(), roomID, claims.User, claims.Rooms)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, echo.Map{"error": "could not load room"})
+			}
+			if role == room.RoleNone {
+				return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+			}
+
+			c.Set(roomContextKey, roomID)
+			c.Set(roleContextKey, role)
+			return next(c)
+		}
+	}
+}
+
+// roomFrom e roleFrom retornam a sala e o papel gravados pelo RoomAccess
+func roomFrom(c echo.Context) string {
+	roomID, _ := c.Get(roomContextKey).(string)
+	return roomID
+}
+
+func roleFrom(c echo.Context) room.Role {
+	role, _ := c.Get(roleContextKey).(room.Role)
+	return role
+}
+
+// targetRoleOf resolve o papel de outro usuário a partir das salas da conta dele
+func targetRoleOf(c echo.Context, rooms room.Store, users user.UserStore, roomID, target string) (room.Role, error) {
+	ctx := c.Request().Context()
+	granted, err := users.AllowedRooms(ctx, target)
+	if err != nil {
+		return room.RoleNone, err
+	}
+	return rooms.RoleOf(ctx, roomID, target, granted)
+}
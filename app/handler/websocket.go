@@ -1,14 +1,16 @@
 package handler
 
 import (
-	"github.com/brunobotter/chat-websocket/redis"
 	"github.com/brunobotter/chat-websocket/websocket"
 	"github.com/labstack/echo/v4"
 )
 
-func WebSocketHandler(hub *websocket.Hub, messageStore redis.MessageStore, publisher redis.Publisher) echo.HandlerFunc {
+// WebSocketHandler deve ser montado atrás do JWTMiddleware e do RoomAccess,
+// para que requisições sem token ou sem acesso à sala recebam 401/403
+// antes do upgrade
+func WebSocketHandler(hub *websocket.Hub) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		websocket.HandleConnections(hub, c.Response().Writer, c.Request(), messageStore, publisher)
+		websocket.HandleConnections(hub, c.Response().Writer, c.Request(), claimsFrom(c), roomFrom(c), roleFrom(c))
 		return nil
 	}
 }
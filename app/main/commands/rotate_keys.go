@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/brunobotter/chat-websocket/auth"
+	"github.com/spf13/cobra"
+)
+
+func NewRotateKeysCommand(tokens *auth.Manager) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Gera uma nova chave de assinatura e aposenta a chave ativa",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kid, err := tokens.Rotate(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("could not rotate signing keys: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "nova chave ativa: %s (assina em %s, depois de publicada no JWKS)\n", kid, auth.JWKSMaxAge)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/brunobotter/chat-websocket/auth"
+	"github.com/brunobotter/chat-websocket/logger"
+	"github.com/brunobotter/chat-websocket/main/container"
+	"github.com/brunobotter/chat-websocket/redis"
+)
+
+type AuthServiceProvider struct{}
+
+func NewAuthServiceProvider() *AuthServiceProvider {
+	return &AuthServiceProvider{}
+}
+
+func (p *AuthServiceProvider) Register(c container.Container) {
+	c.Singleton(func(redisClient *redis.ClientWrapper) auth.KeyStore {
+		return auth.NewRedisKeyStore(redisClient.Client)
+	})
+	c.Singleton(func(redisClient *redis.ClientWrapper) auth.SessionStore {
+		return auth.NewRedisSessionStore(redisClient.Client)
+	})
+	c.Singleton(func(redisClient *redis.ClientWrapper) auth.RevocationStore {
+		return auth.NewRedisRevocationStore(redisClient.Client)
+	})
+	c.Singleton(func(redisClient *redis.ClientWrapper) auth.TicketStore {
+		return auth.NewRedisTicketStore(redisClient.Client)
+	})
+	c.Singleton(func(ctx context.Context, cfg auth.Config, keys auth.KeyStore, sessions auth.SessionStore, revocations auth.RevocationStore, tickets auth.TicketStore, logger logger.Logger) (*auth.Manager, error) {
+		tokens, err := auth.NewManager(cfg, keys, sessions, revocations, tickets)
+		if err != nil {
+			return nil, err
+		}
+		if err := tokens.Reload(ctx); err != nil {
+			return nil, err
+		}
+		go tokens.Watch(ctx, logger)
+
+		logger.InfoF("Tokens de acesso assinados com %s", cfg.Algorithm)
+		return tokens, nil
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/brunobotter/chat-websocket/main/app"
+	"github.com/brunobotter/chat-websocket/main/commands"
 	"github.com/brunobotter/chat-websocket/main/container"
 	"github.com/brunobotter/chat-websocket/main/server"
 	"github.com/brunobotter/chat-websocket/websocket"
@@ -15,7 +16,11 @@ type CliServiceProvider struct {
 }
 
 func NewCliServiceProvider() *CliServiceProvider {
-	return &CliServiceProvider{}
+	return &CliServiceProvider{
+		commands: []any{
+			commands.NewRotateKeysCommand,
+		},
+	}
 }
 
 func (p *CliServiceProvider) Register(c container.Container) {
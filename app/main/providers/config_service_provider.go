@@ -1,10 +1,12 @@
 package providers
 
 import (
+	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/config"
 	"github.com/brunobotter/chat-websocket/logger"
 	"github.com/brunobotter/chat-websocket/main/container"
 	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/websocket"
 )
 
 type ConfigServiceProvider struct{}
@@ -21,14 +23,45 @@ func (p *ConfigServiceProvider) Register(c container.Container) {
 	})
 	c.Singleton(func(cfg *config.Config) redis.RedisConfig {
 		return redis.RedisConfig{
-			Addr:         cfg.Redis.Addr,
-			Password:     cfg.Redis.Password,
-			DB:           cfg.Redis.DB,
-			DialTimeout:  cfg.Redis.DialTimeout,
-			ReadTimeout:  cfg.Redis.ReadTimeout,
-			WriteTimeout: cfg.Redis.WriteTimeout,
-			PoolSize:     cfg.Redis.PoolSize,
-			MinIdleConns: cfg.Redis.MinIdleConns,
+			Addr:           cfg.Redis.Addr,
+			Password:       cfg.Redis.Password,
+			DB:             cfg.Redis.DB,
+			DialTimeout:    cfg.Redis.DialTimeout,
+			ReadTimeout:    cfg.Redis.ReadTimeout,
+			WriteTimeout:   cfg.Redis.WriteTimeout,
+			PoolSize:       cfg.Redis.PoolSize,
+			MinIdleConns:   cfg.Redis.MinIdleConns,
+			UnreadTTL:      cfg.History.UnreadTTL,
+			Transport:      cfg.Redis.Transport,
+			StreamMaxLen:   cfg.Redis.StreamMaxLen,
+			StreamInstance: cfg.Redis.StreamInstance,
+		}
+	})
+	c.Singleton(func(cfg *config.Config) redis.Retention {
+		return redis.Retention{
+			MaxMessages: cfg.History.MaxMessages,
+			MaxAge:      cfg.History.MaxAge,
+		}
+	})
+	c.Singleton(func(cfg *config.Config) auth.Config {
+		return auth.Config{
+			Algorithm:         cfg.Auth.Algorithm,
+			Issuer:            cfg.Auth.Issuer,
+			AccessSecret:      cfg.Auth.AccessSecret,
+			AccessSecretFile:  cfg.Auth.AccessSecretFile,
+			RefreshSecret:     cfg.Auth.RefreshSecret,
+			RefreshSecretFile: cfg.Auth.RefreshSecretFile,
+			PrivateKeyFile:    cfg.Auth.PrivateKeyFile,
+			AccessTTL:         cfg.Auth.AccessTTL,
+			RefreshTTL:        cfg.Auth.RefreshTTL,
+		}
+	})
+	c.Singleton(func(cfg *config.Config) websocket.Config {
+		return websocket.Config{
+			PingInterval:   cfg.WebSocket.PingInterval,
+			PongWait:       cfg.WebSocket.PongWait,
+			WriteWait:      cfg.WebSocket.WriteWait,
+			MaxMessageSize: cfg.WebSocket.MaxMessageSize,
 		}
 	})
 	c.Singleton(func(cfg *config.Config) logger.Logger {
@@ -3,10 +3,14 @@ package providers
 import (
 	"context"
 
+	"github.com/brunobotter/chat-websocket/auth"
+	"github.com/brunobotter/chat-websocket/config"
 	"github.com/brunobotter/chat-websocket/dto"
 	"github.com/brunobotter/chat-websocket/logger"
 	"github.com/brunobotter/chat-websocket/main/container"
+	"github.com/brunobotter/chat-websocket/presence"
 	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
 	"github.com/brunobotter/chat-websocket/websocket"
 )
 
@@ -17,11 +21,24 @@ func NewHubServiceProvider() *HubServiceProvider {
 }
 
 func (p *HubServiceProvider) Register(c container.Container) {
-	c.Singleton(func(logger logger.Logger, redisClient *redis.ClientWrapper) (*websocket.Hub, error) {
-		hub := websocket.NewHub(logger, redisClient)
-		go redisClient.SubscribeAllRooms(context.Background(), func(msg dto.Message) {
+	c.Singleton(func(cfg *config.Config, logger logger.Logger) (*websocket.OriginPolicy, error) {
+		return websocket.NewOriginPolicy(cfg.WebSocket.AllowedOrigins, logger)
+	})
+	c.Singleton(func(logger logger.Logger, redisClient *redis.ClientWrapper, publisher redis.Publisher, subscriber redis.Subscriber, tokens *auth.Manager, rooms room.Store, presence presence.Store, origins *websocket.OriginPolicy, connCfg websocket.Config, retention redis.Retention, cfg *config.Config) (*websocket.Hub, error) {
+		hub := websocket.NewHub(logger, redisClient, publisher, rooms, presence, origins, connCfg, retention)
+		go hub.SweepHistory(context.Background(), cfg.History.SweepInterval)
+		go subscriber.SubscribeAllRooms(context.Background(), func(msg dto.Message) {
 			hub.Broadcast <- msg
 		})
+		go subscriber.SubscribeRoomEvents(context.Background(), func(event dto.RoomEvent) {
+			hub.Events <- event
+		})
+		go tokens.SubscribeRevocations(context.Background(), func(r auth.Revocation) {
+			hub.Revoke <- r
+		})
+		go rooms.SubscribeKicks(context.Background(), func(kick dto.Kick) {
+			hub.Kick <- kick
+		})
 		return hub, nil
 	})
 }
@@ -4,6 +4,9 @@ func List() []any {
 	return []any{
 		NewConfigServiceProvider(),
 		NewRedisServiceProvider(),
+		NewAuthServiceProvider(),
+		NewUserServiceProvider(),
+		NewRoomServiceProvider(),
 		NewHubServiceProvider(),
 		NewCliServiceProvider(),
 	}
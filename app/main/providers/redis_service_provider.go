@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"errors"
+
 	"github.com/brunobotter/chat-websocket/logger"
 	"github.com/brunobotter/chat-websocket/main/container"
 	"github.com/brunobotter/chat-websocket/redis"
@@ -17,6 +19,21 @@ func (p *RedisServiceProvider) Register(c container.Container) {
 		return redis.NewClient(redisConfig, logger)
 	})
 	c.Singleton(func(cw *redis.ClientWrapper) redis.MessageStore { return cw })
-	c.Singleton(func(cw *redis.ClientWrapper) redis.Publisher { return cw })
+	c.Singleton(func(redisConfig redis.RedisConfig, cw *redis.ClientWrapper, logger logger.Logger) (redis.Transport, error) {
+		var transport redis.Transport
+		switch redisConfig.Transport {
+		case redis.TransportStreams:
+			if redisConfig.StreamInstance == "" {
+				return nil, errors.New("REDIS_STREAM_INSTANCE is required for the streams transport")
+			}
+			transport = redis.NewStreamTransport(cw.Client, logger, redisConfig.StreamMaxLen, redisConfig.StreamInstance)
+		default:
+			transport = cw
+		}
+		logger.InfoF("Transporte entre instâncias: %s", redisConfig.Transport)
+		return transport, nil
+	})
+	c.Singleton(func(t redis.Transport) redis.Publisher { return t })
+	c.Singleton(func(t redis.Transport) redis.Subscriber { return t })
 
 }
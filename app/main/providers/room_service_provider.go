@@ -0,0 +1,23 @@
+package providers
+
+import (
+	"github.com/brunobotter/chat-websocket/main/container"
+	"github.com/brunobotter/chat-websocket/presence"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+)
+
+type RoomServiceProvider struct{}
+
+func NewRoomServiceProvider() *RoomServiceProvider {
+	return &RoomServiceProvider{}
+}
+
+func (p *RoomServiceProvider) Register(c container.Container) {
+	c.Singleton(func(redisClient *redis.ClientWrapper) room.Store {
+		return room.NewRedisStore(redisClient.Client)
+	})
+	c.Singleton(func(redisClient *redis.ClientWrapper) presence.Store {
+		return presence.NewRedisStore(redisClient.Client)
+	})
+}
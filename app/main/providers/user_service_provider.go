@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/brunobotter/chat-websocket/config"
+	"github.com/brunobotter/chat-websocket/logger"
+	"github.com/brunobotter/chat-websocket/main/container"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/user"
+)
+
+type UserServiceProvider struct{}
+
+func NewUserServiceProvider() *UserServiceProvider {
+	return &UserServiceProvider{}
+}
+
+func (p *UserServiceProvider) Register(c container.Container) {
+	c.Singleton(func(cfg *config.Config, logger logger.Logger, redisClient *redis.ClientWrapper) (user.UserStore, error) {
+		var store user.UserStore
+		switch cfg.Users.Store {
+		case "memory":
+			store = user.NewMemoryStore()
+		default:
+			store = user.NewRedisStore(redisClient.Client)
+		}
+
+		if err := user.Seed(context.Background(), store, cfg.Users.Seed, cfg.Users.DefaultRooms, cfg.Users.Admins); err != nil {
+			return nil, err
+		}
+		logger.InfoF("User store inicializado: %s", cfg.Users.Store)
+		return store, nil
+	})
+}
@@ -1,18 +1,54 @@
 package router
 
 import (
+	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/config"
 	"github.com/brunobotter/chat-websocket/handler"
-	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/presence"
+	"github.com/brunobotter/chat-websocket/room"
+	"github.com/brunobotter/chat-websocket/user"
 	"github.com/brunobotter/chat-websocket/websocket"
 	"github.com/labstack/echo/v4"
 )
 
-func RegisterRoutes(e *echo.Echo, cfg *config.Config, hub *websocket.Hub, messageStore redis.MessageStore, publisher redis.Publisher) {
+func RegisterRoutes(e *echo.Echo, cfg *config.Config, hub *websocket.Hub, users user.UserStore, tokens *auth.Manager, rooms room.Store, online presence.Store) {
+	jwt := handler.JWTMiddleware(tokens)
+
 	// Rotas públicas
-	e.POST("/login", handler.Login)
-	e.POST("/refresh", handler.Refresh)
+	e.POST("/login", handler.Login(users, tokens))
+	e.POST("/refresh", handler.Refresh(users, tokens))
+	e.POST("/register", handler.Register(users, cfg.Users.DefaultRooms))
+	e.POST("/logout", handler.Logout(tokens))
+	e.POST("/logout/all", handler.LogoutAll(tokens))
+	e.POST("/password/reset", handler.ResetPassword(users, tokens))
+	e.GET("/.well-known/jwks.json", handler.JWKS(tokens))
+	e.GET("/health", handler.Health(hub))
 
 	// Rotas protegidas
-	e.GET("/ws", handler.WebSocketHandler(hub, messageStore, publisher))
+	e.POST("/password/change", handler.ChangePassword(users, tokens), jwt)
+	e.POST("/tokens/revoke", handler.RevokeToken(tokens), jwt)
+	e.POST("/ws/ticket", handler.IssueTicket(tokens), jwt)
+	e.POST("/admin/users/:user/password-reset", handler.RequestPasswordReset(users, cfg.Users.ResetTokenTTL), jwt)
+	e.PUT("/admin/users/:user/rooms/:room", handler.GrantRoom(users, rooms), jwt)
+	e.DELETE("/admin/users/:user/rooms/:room", handler.RemoveRoom(users, rooms), jwt)
+	e.POST("/rooms", handler.CreateRoom(rooms, users), jwt)
+	e.GET("/unread", handler.UnreadCounts(hub.ChatStore), jwt)
+
+	// Rotas protegidas por sala
+	roomAccess := handler.RoomAccess(rooms)
+	r := e.Group("/rooms/:id", jwt, roomAccess)
+	r.GET("", handler.GetRoom(rooms))
+	r.PUT("/topic", handler.UpdateTopic(rooms))
+	r.PUT("/retention", handler.UpdateRetention(rooms))
+	r.DELETE("/retention", handler.ResetRetention(rooms))
+	r.PUT("/members/:user", handler.InviteMember(rooms, users))
+	r.DELETE("/members/:user", handler.KickMember(rooms, users))
+	r.PUT("/members/:user/role", handler.SetMemberRole(rooms, users))
+	r.GET("/presence", handler.RoomPresence(online))
+	r.GET("/receipts", handler.ReadReceipts(hub.ChatStore))
+	r.GET("/messages", handler.GetMessages(hub.ChatStore))
+	r.GET("/threads/:msgId", handler.GetThread(hub.ChatStore))
+
+	// token e acesso à sala são verificados antes do upgrade
+	e.GET("/ws", handler.WebSocketHandler(hub), jwt, roomAccess)
 }
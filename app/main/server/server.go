@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/config"
 	"github.com/brunobotter/chat-websocket/logger"
 	"github.com/brunobotter/chat-websocket/main/container"
 	"github.com/brunobotter/chat-websocket/main/server/router"
-	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/presence"
+	"github.com/brunobotter/chat-websocket/room"
+	"github.com/brunobotter/chat-websocket/user"
 	"github.com/brunobotter/chat-websocket/websocket"
 	"github.com/labstack/echo/v4"
 )
@@ -40,14 +43,18 @@ func (s *Server) setup() {
 
 	var cfg *config.Config
 	var hub *websocket.Hub
-	var messageStore redis.MessageStore
-	var publisher redis.Publisher
+	var users user.UserStore
+	var tokens *auth.Manager
+	var rooms room.Store
+	var online presence.Store
 
 	s.container.Resolve(&cfg)
 	s.container.Resolve(&hub)
-	s.container.Resolve(&messageStore)
-	s.container.Resolve(&publisher)
-	router.RegisterRoutes(s.echo, cfg, hub, messageStore, publisher)
+	s.container.Resolve(&users)
+	s.container.Resolve(&tokens)
+	s.container.Resolve(&rooms)
+	s.container.Resolve(&online)
+	router.RegisterRoutes(s.echo, cfg, hub, users, tokens, rooms, online)
 
 }
 
@@ -0,0 +1,145 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTL é quanto uma entrada de presença vale sem heartbeat; entradas de uma
+// instância que caiu deixam de contar depois desse prazo
+const TTL = 30 * time.Second
+
+const (
+	Online  = "online"
+	Offline = "offline"
+)
+
+// Interface para a presença dos usuários nas salas, compartilhada entre as instâncias
+type Store interface {
+	Join(ctx context.Context, instance, roomID, user string) error
+	Leave(ctx context.Context, instance, roomID, user string) error
+	Heartbeat(ctx context.Context, instance string, rooms map[string][]string) error
+	Online(ctx context.Context, roomID string) ([]string, error)
+	IsOnline(ctx context.Context, roomID, user string) (bool, error)
+}
+
+// RedisStore guarda, por sala, um sorted set com as instâncias
+// (presence:<sala>:instances) e um com os usuários de cada instância
+// (presence:<sala>:<instância>), sempre com o horário do último heartbeat como score
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func instancesKey(roomID string) string {
+	return "presence:" + roomID + ":instances"
+}
+
+func usersKey(roomID, instance string) string {
+	return "presence:" + roomID + ":" + instance
+}
+
+func (s *RedisStore) Join(ctx context.Context, instance, roomID, user string) error {
+	now := float64(time.Now().UnixMilli())
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, usersKey(roomID, instance), redis.Z{Score: now, Member: user})
+	pipe.Expire(ctx, usersKey(roomID, instance), TTL)
+	pipe.ZAdd(ctx, instancesKey(roomID), redis.Z{Score: now, Member: instance})
+	pipe.Expire(ctx, instancesKey(roomID), TTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Leave(ctx context.Context, instance, roomID, user string) error {
+	return s.client.ZRem(ctx, usersKey(roomID, instance), user).Err()
+}
+
+// Heartbeat renova as entradas dos usuários conectados nesta instância e
+// descarta os que não foram renovados a tempo
+func (s *RedisStore) Heartbeat(ctx context.Context, instance string, rooms map[string][]string) error {
+	now := time.Now()
+	score := float64(now.UnixMilli())
+	cutoff := strconv.FormatInt(now.Add(-TTL).UnixMilli(), 10)
+
+	pipe := s.client.Pipeline()
+	for roomID, users := range rooms {
+		if len(users) == 0 {
+			continue
+		}
+		members := make([]redis.Z, 0, len(users))
+		for _, user := range users {
+			members = append(members, redis.Z{Score: score, Member: user})
+		}
+		pipe.ZAdd(ctx, usersKey(roomID, instance), members...)
+		pipe.ZRemRangeByScore(ctx, usersKey(roomID, instance), "-inf", "("+cutoff)
+		pipe.Expire(ctx, usersKey(roomID, instance), TTL)
+		pipe.ZAdd(ctx, instancesKey(roomID), redis.Z{Score: score, Member: instance})
+		pipe.ZRemRangeByScore(ctx, instancesKey(roomID), "-inf", "("+cutoff)
+		pipe.Expire(ctx, instancesKey(roomID), TTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Online lista os usuários presentes na sala em qualquer instância
+func (s *RedisStore) Online(ctx context.Context, roomID string) ([]string, error) {
+	instances, cutoff, err := s.liveInstances(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []string{}
+	for _, instance := range instances {
+		members, err := s.client.ZRangeByScore(ctx, usersKey(roomID, instance), &redis.ZRangeBy{Min: cutoff, Max: "+inf"}).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range members {
+			if !slices.Contains(users, user) {
+				users = append(users, user)
+			}
+		}
+	}
+	slices.Sort(users)
+	return users, nil
+}
+
+func (s *RedisStore) IsOnline(ctx context.Context, roomID, user string) (bool, error) {
+	instances, cutoff, err := s.liveInstances(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	minScore, _ := strconv.ParseFloat(cutoff, 64)
+
+	for _, instance := range instances {
+		score, err := s.client.ZScore(ctx, usersKey(roomID, instance), user).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if score >= minScore {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// liveInstances lista as instâncias com heartbeat dentro do TTL
+func (s *RedisStore) liveInstances(ctx context.Context, roomID string) ([]string, string, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-TTL).UnixMilli(), 10)
+	instances, err := s.client.ZRangeByScore(ctx, instancesKey(roomID), &redis.ZRangeBy{Min: cutoff, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	return instances, cutoff, nil
+}
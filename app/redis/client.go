@@ -18,6 +18,12 @@ type RedisConfig struct {
 	WriteTimeout time.Duration
 	PoolSize     int
 	MinIdleConns int
+	UnreadTTL    time.Duration
+	Transport    string
+	StreamMaxLen int64
+	// StreamInstance identifica a instância no cursor dos streams; precisa ser
+	// único e se manter entre restarts
+	StreamInstance string
 }
 
 func NewClient(cfg RedisConfig, logger logger.Logger) (*ClientWrapper, error) {
@@ -46,7 +52,8 @@ func NewClient(cfg RedisConfig, logger logger.Logger) (*ClientWrapper, error) {
 	logger.Info("Redis conectado com sucesso", zap.String("addr", cfg.Addr))
 
 	return &ClientWrapper{
-		Client: rdb,
-		Logger: logger,
+		Client:    rdb,
+		Logger:    logger,
+		UnreadTTL: cfg.UnreadTTL,
 	}, nil
 }
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrMessageNotFound = errors.New("redis: message not found")
+
+// UpdateMessage aplica update à mensagem id do histórico da sala (ou da
+// thread, para respostas) e grava o
+// resultado no mesmo lugar da lista, dentro de um WATCH; um erro de update
+// cancela a alteração. A cópia na lista de não lidas do destinatário de uma
+// mensagem privada também é atualizada.
+func (cw *ClientWrapper) UpdateMessage(ctx context.Context, roomID, id string, update func(*dto.Message) error) (dto.Message, error) {
+	updated, err := cw.updateListMessage(ctx, messageListKey(roomID, id), id, update)
+	if err != nil {
+		return dto.Message{}, err
+	}
+
+	if updated.Target != "" {
+		_, err := cw.updateListMessage(ctx, "unread:"+updated.Target, id, func(msg *dto.Message) error {
+			*msg = updated
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrMessageNotFound) {
+			return dto.Message{}, err
+		}
+	}
+
+	decorated, err := cw.withAggregates(ctx, roomID, []dto.Message{updated})
+	if err != nil {
+		return updated, nil
+	}
+	return decorated[0], nil
+}
+
+// maxUpdateRetries limita as novas tentativas quando a lista muda durante a
+// alteração, o que acontece a cada mensagem nova em salas movimentadas
+const maxUpdateRetries = 10
+
+// updateListMessage reescreve a mensagem na lista com WATCH; se a lista mudar
+// no meio (LPUSH de uma mensagem nova), a mensagem é procurada de novo
+func (cw *ClientWrapper) updateListMessage(ctx context.Context, key, id string, update func(*dto.Message) error) (dto.Message, error) {
+	for range maxUpdateRetries {
+		updated, err := cw.tryUpdateListMessage(ctx, key, id, update)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return updated, err
+		}
+	}
+	return dto.Message{}, redis.TxFailedErr
+}
+
+func (cw *ClientWrapper) tryUpdateListMessage(ctx context.Context, key, id string, update func(*dto.Message) error) (dto.Message, error) {
+	var updated dto.Message
+	err := cw.Client.Watch(ctx, func(tx *redis.Tx) error {
+		vals, err := tx.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, val := range vals {
+			var msg dto.Message
+			if err := json.Unmarshal([]byte(val), &msg); err != nil || msg.ID != id {
+				continue
+			}
+			if err := update(&msg); err != nil {
+				return err
+			}
+
+			payload, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.LSet(ctx, key, int64(i), payload)
+				return nil
+			})
+			updated = msg
+			return err
+		}
+		return ErrMessageNotFound
+	}, key)
+	return updated, err
+}
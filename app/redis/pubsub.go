@@ -3,7 +3,10 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/brunobotter/chat-websocket/dto"
@@ -11,29 +14,58 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+var (
+	ErrInvalidMessageID = errors.New("redis: invalid message id")
+	// ErrHistoryGap indica que parte das mensagens posteriores ao ID informado
+	// já saiu do histórico (LTRIM ou expiração)
+	ErrHistoryGap = errors.New("redis: history gap")
+)
+
 // Interface para publicação
 type Publisher interface {
 	PublishMessage(ctx context.Context, channel string, msg dto.Message) error
+	PublishEvent(ctx context.Context, channel string, event dto.RoomEvent) error
 }
 
 // Interface para subscribe
 type Subscriber interface {
 	SubscribeAllRooms(ctx context.Context, handler func(dto.Message))
+	SubscribeRoomEvents(ctx context.Context, handler func(dto.RoomEvent))
 }
 
 // Interface para persistência
 type MessageStore interface {
-	SaveMessage(ctx context.Context, roomID string, msg dto.Message, maxMessages int) error
-	GetMessages(ctx context.Context, roomID string, limit int) ([]dto.Message, error)
+	NextMessageID(ctx context.Context, roomID string) (string, error)
+	SaveMessage(ctx context.Context, roomID string, msg dto.Message, retention Retention) error
+	ClaimClientMsgID(ctx context.Context, roomID, user, clientMsgID, messageID string) (SentMessage, error)
+	SetClientMsgID(ctx context.Context, roomID, user, clientMsgID string, sent SentMessage) error
+	GetMessages(ctx context.Context, roomID string, limit int, viewer string) ([]dto.Message, error)
+	GetMessagesSince(ctx context.Context, roomID, sinceID, viewer string) ([]dto.Message, error)
+	GetMessagesBefore(ctx context.Context, roomID, before string, limit int, viewer string) ([]dto.Message, bool, error)
+	GetMessage(ctx context.Context, roomID, id string) (dto.Message, error)
+	UpdateMessage(ctx context.Context, roomID, id string, update func(*dto.Message) error) (dto.Message, error)
+	NextReplyID(ctx context.Context, roomID, parentID string) (string, error)
+	SaveThreadReply(ctx context.Context, roomID string, msg dto.Message, retention Retention) (int64, error)
+	GetThread(ctx context.Context, roomID, parentID, before string, limit int) ([]dto.Message, bool, error)
+	React(ctx context.Context, roomID, id, user, emoji string, retention Retention) (bool, error)
+	Unreact(ctx context.Context, roomID, id, user, emoji string) (bool, error)
 	SaveUnread(ctx context.Context, user string, msg dto.Message) error
 	GetUnreadMessages(ctx context.Context, user string) ([]dto.Message, error)
 	ClearUnread(ctx context.Context, user string) error
+	AdvanceReadCursor(ctx context.Context, roomID, user, messageID string) (bool, error)
+	ReadCursors(ctx context.Context, roomID string) (map[string]string, error)
+	UnreadCounts(ctx context.Context, user string, rooms []string) (map[string]int64, error)
+	TrimHistory(ctx context.Context, roomID string, retention Retention) (int, error)
+	HistoryRooms(ctx context.Context) ([]string, error)
+	AcquireSweepLock(ctx context.Context, ttl time.Duration) (bool, error)
 	Close() error
 }
 
 type ClientWrapper struct {
 	Client *redis.Client
 	Logger logger.Logger
+	// UnreadTTL é por quanto tempo as mensagens privadas não lidas são mantidas
+	UnreadTTL time.Duration
 }
 
 func (cw *ClientWrapper) SubscribeAllRooms(ctx context.Context, handler func(dto.Message)) {
@@ -65,7 +97,45 @@ func (cw *ClientWrapper) SubscribeAllRooms(ctx context.Context, handler func(dto
 	}
 }
 
-func (cw *ClientWrapper) SaveMessage(ctx context.Context, roomID string, msg dto.Message, maxMessages int) error {
+// NextMessageID gera o ID da próxima mensagem da sala a partir de um contador
+// no Redis, compartilhado entre as instâncias; os IDs crescem na ordem de envio
+func (cw *ClientWrapper) NextMessageID(ctx context.Context, roomID string) (string, error) {
+	seq, err := cw.Client.Incr(ctx, "chat:"+roomID+":seq").Result()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(seq, 10), nil
+}
+
+// SubscribeRoomEvents recebe os eventos efêmeros de todas as salas, publicados
+// em events:<sala>, separados das mensagens de chat:*
+func (cw *ClientWrapper) SubscribeRoomEvents(ctx context.Context, handler func(dto.RoomEvent)) {
+	pubsub := cw.Client.PSubscribe(ctx, "events:*")
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var event dto.RoomEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			handler(event)
+		}
+	}
+}
+
+// SaveMessage grava a mensagem no histórico da sala aplicando a política de
+// retenção: o excedente de quantidade sai junto com suas reações e thread, e a
+// idade máxima vira o TTL da lista
+func (cw *ClientWrapper) SaveMessage(ctx context.Context, roomID string, msg dto.Message, retention Retention) error {
 	key := "chat:" + roomID
 
 	payload, err := json.Marshal(msg)
@@ -73,41 +143,182 @@ func (cw *ClientWrapper) SaveMessage(ctx context.Context, roomID string, msg dto
 		return err
 	}
 
+	pipe := cw.Client.TxPipeline()
 	// LPUSH adiciona no início da lista
-	if err := cw.Client.LPush(ctx, key, payload).Err(); err != nil {
+	pipe.LPush(ctx, key, payload)
+	var overflow *redis.StringSliceCmd
+	if retention.MaxMessages > 0 {
+		overflow = pipe.LRange(ctx, key, int64(retention.MaxMessages), -1)
+		pipe.LTrim(ctx, key, 0, int64(retention.MaxMessages-1))
+	}
+	retention.expire(ctx, pipe, key)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return err
 	}
 
-	// LTRIM mantém apenas as últimas `maxMessages`
-	if err := cw.Client.LTrim(ctx, key, 0, int64(maxMessages-1)).Err(); err != nil {
-		return err
+	if overflow != nil {
+		return cw.dropRelated(ctx, roomID, overflow.Val())
 	}
+	return nil
+}
 
-	if err := cw.Client.Expire(ctx, key, 6*time.Hour).Err(); err != nil {
-		return err
+// GetMessages retorna as últimas `limit` mensagens da sala visíveis para
+// viewer, com as reações agregadas e o total de respostas; limit <= 0 retorna
+// todas
+func (cw *ClientWrapper) GetMessages(ctx context.Context, roomID string, limit int, viewer string) ([]dto.Message, error) {
+	var messages []dto.Message
+	err := cw.walkHistory(ctx, roomID, nil, func(msg dto.Message, id int64) bool {
+		if !msg.VisibleTo(viewer) {
+			return true
+		}
+		if limit > 0 && len(messages) == limit {
+			return false
+		}
+		messages = append(messages, msg)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	slices.Reverse(messages)
+	return cw.withAggregates(ctx, roomID, messages)
 }
 
-func (cw *ClientWrapper) GetMessages(ctx context.Context, roomID string, limit int) ([]dto.Message, error) {
-	key := "chat:" + roomID
+// GetMessagesSince retorna as mensagens da sala visíveis para viewer
+// posteriores a sinceID, da mais antiga para a mais nova. Quando a mensagem
+// mais antiga mantida no histórico é posterior à seguinte a sinceID, parte
+// delas pode ter sido descartada: retorna as que restaram junto com
+// ErrHistoryGap.
+func (cw *ClientWrapper) GetMessagesSince(ctx context.Context, roomID, sinceID, viewer string) ([]dto.Message, error) {
+	since, err := strconv.ParseInt(sinceID, 10, 64)
+	if err != nil || since < 0 {
+		return nil, ErrInvalidMessageID
+	}
 
-	vals, err := cw.Client.LRange(ctx, key, 0, int64(limit-1)).Result()
+	last, err := cw.Client.Get(ctx, "chat:"+roomID+":seq").Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if since >= last {
+		return []dto.Message{}, nil
+	}
+
+	// oldest termina com o ID da mais antiga lida, visível ou não para viewer:
+	// a primeira até since ou, se o histórico acabar antes, a mais antiga mantida
+	var oldest int64
+	var messages []dto.Message
+	err = cw.walkHistory(ctx, roomID, nil, func(msg dto.Message, id int64) bool {
+		oldest = id
+		if id <= since {
+			return false
+		}
+		if msg.VisibleTo(viewer) {
+			messages = append(messages, msg)
+		}
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	messages := make([]dto.Message, 0, len(vals))
-	for i := len(vals) - 1; i >= 0; i-- {
-		var msg dto.Message
-		if err := json.Unmarshal([]byte(vals[i]), &msg); err != nil {
-			continue
+	slices.Reverse(messages)
+	messages, err = cw.withAggregates(ctx, roomID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs consumidos sem a mensagem ser gravada não contam como lacuna; o
+	// histórico vazio com mensagens após since quer dizer que elas expiraram
+	if oldest == 0 || oldest > since+1 {
+		return messages, ErrHistoryGap
+	}
+	return messages, nil
+}
+
+// GetMessagesBefore pagina o histórico da sala para trás: até limit mensagens
+// visíveis para viewer anteriores a before (as mais recentes quando before é
+// vazio), da mais antiga para a mais nova, e se ainda há mensagens mais antigas
+func (cw *ClientWrapper) GetMessagesBefore(ctx context.Context, roomID, before string, limit int, viewer string) ([]dto.Message, bool, error) {
+	var cursor int64
+	if before != "" {
+		id, err := strconv.ParseInt(before, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, false, ErrInvalidMessageID
+		}
+		cursor = id
+	}
+
+	messages := make([]dto.Message, 0, limit)
+	more := false
+	err := cw.walkHistory(ctx, roomID, nil, func(msg dto.Message, id int64) bool {
+		if (cursor > 0 && id >= cursor) || !msg.VisibleTo(viewer) {
+			return true
+		}
+		if len(messages) == limit {
+			more = true
+			return false
 		}
 		messages = append(messages, msg)
+		return true
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	return messages, nil
+	slices.Reverse(messages)
+	messages, err = cw.withAggregates(ctx, roomID, messages)
+	return messages, more, err
+}
+
+// historyPage é o tamanho de cada janela de LRANGE ao percorrer o histórico de
+// uma sala, para que as leituras parem assim que tiverem o que precisam em vez
+// de carregar a lista inteira
+const historyPage = 100
+
+// walkHistory percorre o histórico da sala da mensagem mais nova para a mais
+// antiga, em janelas de historyPage, até visit retornar false ou a lista
+// acabar. first é a primeira janela quando ela já foi lida num pipeline. Uma
+// mensagem gravada entre duas janelas desloca a lista, então as mensagens já
+// vistas são ignoradas.
+func (cw *ClientWrapper) walkHistory(ctx context.Context, roomID string, first []string, visit func(msg dto.Message, id int64) bool) error {
+	key := "chat:" + roomID
+	seen := make(map[string]struct{})
+	vals := first
+	var start int64
+	for {
+		if vals == nil {
+			page, err := cw.Client.LRange(ctx, key, start, start+historyPage-1).Result()
+			if err != nil {
+				return err
+			}
+			vals = page
+		}
+
+		for _, val := range vals {
+			var msg dto.Message
+			if err := json.Unmarshal([]byte(val), &msg); err != nil {
+				continue
+			}
+			id, err := strconv.ParseInt(msg.ID, 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[msg.ID]; ok {
+				continue
+			}
+			seen[msg.ID] = struct{}{}
+			if !visit(msg, id) {
+				return nil
+			}
+		}
+
+		if len(vals) < historyPage {
+			return nil
+		}
+		start += historyPage
+		vals = nil
+	}
 }
 
 // SaveUnread adiciona uma mensagem privada à lista de mensagens não lidas do usuário
@@ -123,8 +334,10 @@ func (cw *ClientWrapper) SaveUnread(ctx context.Context, user string, msg dto.Me
 		return err
 	}
 
-	if err := cw.Client.Expire(ctx, key, 24*time.Hour).Err(); err != nil {
-		return err
+	if cw.UnreadTTL > 0 {
+		if err := cw.Client.Expire(ctx, key, cw.UnreadTTL).Err(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -174,6 +387,14 @@ func (cw *ClientWrapper) PublishMessage(ctx context.Context, channel string, msg
 	return nil
 }
 
+func (cw *ClientWrapper) PublishEvent(ctx context.Context, channel string, event dto.RoomEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return cw.Client.Publish(ctx, channel, payload).Err()
+}
+
 func (cw *ClientWrapper) Close() error {
 	return cw.Client.Close()
 }
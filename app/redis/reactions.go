@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+// reactionsKey guarda as reações de uma mensagem como um set de "<usuário>:<emoji>";
+// nomes de usuário não têm ":", então o primeiro separa os dois
+func reactionsKey(roomID, id string) string {
+	return "reactions:" + roomID + ":" + id
+}
+
+// GetMessage busca uma mensagem que ainda está no histórico da sala ou da thread
+func (cw *ClientWrapper) GetMessage(ctx context.Context, roomID, id string) (dto.Message, error) {
+	vals, err := cw.Client.LRange(ctx, messageListKey(roomID, id), 0, -1).Result()
+	if err != nil {
+		return dto.Message{}, err
+	}
+	for _, val := range vals {
+		var msg dto.Message
+		if err := json.Unmarshal([]byte(val), &msg); err == nil && msg.ID == id {
+			decorated, err := cw.withAggregates(ctx, roomID, []dto.Message{msg})
+			if err != nil {
+				return dto.Message{}, err
+			}
+			return decorated[0], nil
+		}
+	}
+	return dto.Message{}, ErrMessageNotFound
+}
+
+// React adiciona a reação do usuário; retorna false se ela já existia
+func (cw *ClientWrapper) React(ctx context.Context, roomID, id, user, emoji string, retention Retention) (bool, error) {
+	key := reactionsKey(roomID, id)
+	pipe := cw.Client.TxPipeline()
+	added := pipe.SAdd(ctx, key, user+":"+emoji)
+	retention.expire(ctx, pipe, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return added.Val() == 1, nil
+}
+
+// Unreact remove a reação do usuário; retorna false se ela não existia
+func (cw *ClientWrapper) Unreact(ctx context.Context, roomID, id, user, emoji string) (bool, error) {
+	removed, err := cw.Client.SRem(ctx, reactionsKey(roomID, id), user+":"+emoji).Result()
+	if err != nil {
+		return false, err
+	}
+	return removed == 1, nil
+}
+
+// withAggregates agrega as reações de cada mensagem por emoji e conta as
+// respostas das mensagens que abrem thread
+func (cw *ClientWrapper) withAggregates(ctx context.Context, roomID string, messages []dto.Message) ([]dto.Message, error) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	pipe := cw.Client.Pipeline()
+	reactions := make([]*redis.StringSliceCmd, len(messages))
+	replies := make([]*redis.IntCmd, len(messages))
+	for i, msg := range messages {
+		reactions[i] = pipe.SMembers(ctx, reactionsKey(roomID, msg.ID))
+		if msg.ParentID == "" {
+			replies[i] = pipe.LLen(ctx, threadKey(roomID, msg.ID))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		messages[i].Reactions = aggregateReactions(reactions[i].Val())
+		if replies[i] != nil {
+			messages[i].ReplyCount = replies[i].Val()
+		}
+	}
+	return messages, nil
+}
+
+func aggregateReactions(members []string) []dto.Reaction {
+	if len(members) == 0 {
+		return nil
+	}
+
+	byEmoji := make(map[string][]string)
+	for _, member := range members {
+		user, emoji, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		byEmoji[emoji] = append(byEmoji[emoji], user)
+	}
+
+	reactions := make([]dto.Reaction, 0, len(byEmoji))
+	for emoji, users := range byEmoji {
+		sort.Strings(users)
+		reactions = append(reactions, dto.Reaction{Emoji: emoji, Count: len(users), Users: users})
+	}
+	sort.Slice(reactions, func(i, j int) bool { return reactions[i].Emoji < reactions[j].Emoji })
+	return reactions
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+// advanceCursorScript só move o cursor para frente e recusa IDs que a sala
+// ainda não gerou. Retorna 1 quando o cursor avançou, 0 quando já estava à
+// frente e -1 para um ID inválido.
+var advanceCursorScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[2]) or "0")
+local id = tonumber(ARGV[2])
+if id > last then
+	return -1
+end
+local current = tonumber(redis.call("HGET", KEYS[1], ARGV[1]) or "0")
+if current >= id then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+func cursorsKey(roomID string) string {
+	return "read:" + roomID
+}
+
+// AdvanceReadCursor grava a última mensagem lida pelo usuário na sala;
+// retorna false quando o cursor já estava nessa mensagem ou depois dela
+func (cw *ClientWrapper) AdvanceReadCursor(ctx context.Context, roomID, user, messageID string) (bool, error) {
+	id, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil || id <= 0 {
+		return false, ErrInvalidMessageID
+	}
+
+	res, err := advanceCursorScript.Run(ctx, cw.Client, []string{cursorsKey(roomID), "chat:" + roomID + ":seq"}, user, id).Int()
+	if err != nil {
+		return false, err
+	}
+	if res < 0 {
+		return false, ErrInvalidMessageID
+	}
+	return res == 1, nil
+}
+
+// ReadCursors retorna a última mensagem lida por cada usuário da sala
+func (cw *ClientWrapper) ReadCursors(ctx context.Context, roomID string) (map[string]string, error) {
+	return cw.Client.HGetAll(ctx, cursorsKey(roomID)).Result()
+}
+
+// UnreadCounts conta, para cada sala, as mensagens do histórico posteriores
+// ao cursor do usuário e visíveis para ele; privadas entre outros usuários e
+// IDs gerados sem a mensagem ser gravada não contam
+func (cw *ClientWrapper) UnreadCounts(ctx context.Context, user string, rooms []string) (map[string]int64, error) {
+	pipe := cw.Client.Pipeline()
+	histories := make([]*redis.StringSliceCmd, len(rooms))
+	cursors := make([]*redis.StringCmd, len(rooms))
+	for i, roomID := range rooms {
+		histories[i] = pipe.LRange(ctx, "chat:"+roomID, 0, historyPage-1)
+		cursors[i] = pipe.HGet(ctx, cursorsKey(roomID), user)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	// a primeira janela de cada sala vem no pipeline; as seguintes só são
+	// lidas quando o cursor está mais para trás
+	counts := make(map[string]int64, len(rooms))
+	for i, roomID := range rooms {
+		cursor, _ := cursors[i].Int64()
+		var count int64
+		err := cw.walkHistory(ctx, roomID, histories[i].Val(), func(msg dto.Message, id int64) bool {
+			if id <= cursor {
+				return false
+			}
+			if msg.VisibleTo(user) {
+				count++
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		counts[roomID] = count
+	}
+	return counts, nil
+}
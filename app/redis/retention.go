@@ -0,0 +1,181 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidRetention = errors.New("invalid retention policy")
+
+// Retention é a política de histórico aplicada a uma sala; campos zerados não
+// limitam. A idade vale como TTL das chaves na escrita e como corte no sweeper.
+type Retention struct {
+	MaxMessages int
+	MaxAge      time.Duration
+}
+
+// ParseRetention valida a política recebida pela API
+func ParseRetention(r dto.Retention) (Retention, error) {
+	if r.MaxMessages < 0 {
+		return Retention{}, ErrInvalidRetention
+	}
+
+	policy := Retention{MaxMessages: r.MaxMessages}
+	if r.MaxAge != "" {
+		age, err := time.ParseDuration(r.MaxAge)
+		if err != nil || age < 0 {
+			return Retention{}, ErrInvalidRetention
+		}
+		policy.MaxAge = age
+	}
+	return policy, nil
+}
+
+// expire renova o TTL da chave; sem idade máxima a chave deixa de expirar
+func (r Retention) expire(ctx context.Context, pipe redis.Pipeliner, key string) {
+	if r.MaxAge > 0 {
+		pipe.Expire(ctx, key, r.MaxAge)
+		return
+	}
+	pipe.Persist(ctx, key)
+}
+
+// sweepLockKey garante que só uma instância aplique a retenção por intervalo
+const sweepLockKey = "history:sweep:lock"
+
+// AcquireSweepLock reserva a próxima varredura de retenção para esta instância
+// por ttl; as outras instâncias pulam a varredura enquanto a chave existir
+func (cw *ClientWrapper) AcquireSweepLock(ctx context.Context, ttl time.Duration) (bool, error) {
+	return cw.Client.SetNX(ctx, sweepLockKey, time.Now().Format(time.RFC3339), ttl).Result()
+}
+
+// popTailScript remove do fim da lista as entradas de ARGV (da mais antiga
+// para a mais nova) enquanto o fim ainda for a entrada esperada, para não
+// apagar outra mensagem se a lista mudou depois da leitura
+var popTailScript = redis.NewScript(`
+local popped = 0
+for i = 1, #ARGV do
+	if redis.call("LINDEX", KEYS[1], -1) ~= ARGV[i] then
+		break
+	end
+	redis.call("RPOP", KEYS[1])
+	popped = popped + 1
+end
+return popped
+`)
+
+// TrimHistory aplica a política ao histórico já gravado da sala, removendo as
+// mensagens que passaram do limite de quantidade ou de idade, e retorna
+// quantas foram removidas. As mais antigas ficam no fim da lista, então só o
+// fim é lido, em janelas de historyPage.
+func (cw *ClientWrapper) TrimHistory(ctx context.Context, roomID string, retention Retention) (int, error) {
+	key := "chat:" + roomID
+	var removed []string
+
+	if retention.MaxMessages > 0 {
+		pipe := cw.Client.TxPipeline()
+		overflow := pipe.LRange(ctx, key, int64(retention.MaxMessages), -1)
+		pipe.LTrim(ctx, key, 0, int64(retention.MaxMessages-1))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+		removed = overflow.Val()
+	}
+
+	if retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-retention.MaxAge)
+		for {
+			vals, err := cw.Client.LRange(ctx, key, -historyPage, -1).Result()
+			if err != nil {
+				return len(removed), err
+			}
+			expired := expiredTail(vals, cutoff)
+			if len(expired) == 0 {
+				break
+			}
+
+			args := make([]any, len(expired))
+			for i, val := range expired {
+				args[i] = val
+			}
+			popped, err := popTailScript.Run(ctx, cw.Client, []string{key}, args...).Int()
+			if err != nil {
+				return len(removed), err
+			}
+			removed = append(removed, expired[:popped]...)
+			// só há mais a remover quando a janela inteira expirou e saiu
+			if popped < len(expired) || len(expired) < len(vals) {
+				break
+			}
+		}
+	}
+
+	return len(removed), cw.dropRelated(ctx, roomID, removed)
+}
+
+// expiredTail retorna as mensagens do fim da janela (as mais antigas)
+// anteriores a cutoff, da mais antiga para a mais nova; entradas ilegíveis
+// também saem, para não travar o corte por idade
+func expiredTail(vals []string, cutoff time.Time) []string {
+	var expired []string
+	for i := len(vals) - 1; i >= 0; i-- {
+		var msg dto.Message
+		if err := json.Unmarshal([]byte(vals[i]), &msg); err == nil && !msg.Timestamp.Before(cutoff) {
+			break
+		}
+		expired = append(expired, vals[i])
+	}
+	return expired
+}
+
+// dropRelated apaga as reações e a thread das mensagens que saíram do
+// histórico, incluindo as reações das respostas
+func (cw *ClientWrapper) dropRelated(ctx context.Context, roomID string, vals []string) error {
+	if len(vals) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(vals)*3)
+	for _, val := range vals {
+		var msg dto.Message
+		if err := json.Unmarshal([]byte(val), &msg); err != nil {
+			continue
+		}
+		thread := threadKey(roomID, msg.ID)
+		replies, err := cw.Client.LRange(ctx, thread, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			var r dto.Message
+			if err := json.Unmarshal([]byte(reply), &r); err == nil {
+				keys = append(keys, reactionsKey(roomID, r.ID))
+			}
+		}
+		keys = append(keys, reactionsKey(roomID, msg.ID), thread, thread+":seq")
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return cw.Client.Del(ctx, keys...).Err()
+}
+
+// HistoryRooms lista as salas que têm histórico gravado
+func (cw *ClientWrapper) HistoryRooms(ctx context.Context) ([]string, error) {
+	var rooms []string
+	iter := cw.Client.Scan(ctx, 0, "chat:*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.HasSuffix(key, ":seq") {
+			continue
+		}
+		rooms = append(rooms, strings.TrimPrefix(key, "chat:"))
+	}
+	return rooms, iter.Err()
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sentTTL é por quanto tempo um client_msg_id continua associado à mensagem,
+// tempo de sobra para o cliente reenviar depois de um erro ou de reconectar
+const sentTTL = time.Hour
+
+// SentMessage é a mensagem associada a um client_msg_id do usuário na sala
+type SentMessage struct {
+	MessageID string
+	Published bool
+}
+
+func sentKey(roomID, user, clientMsgID string) string {
+	return "sent:" + roomID + ":" + user + ":" + clientMsgID
+}
+
+// claimSentScript associa o client_msg_id ao ID novo só se ele ainda não
+// tiver um; retorna o ID associado e se a mensagem já foi publicada
+var claimSentScript = redis.NewScript(`
+local id = redis.call("HGET", KEYS[1], "id")
+if id then
+	return {id, redis.call("HGET", KEYS[1], "published") or "0"}
+end
+redis.call("HSET", KEYS[1], "id", ARGV[1], "published", "0")
+redis.call("EXPIRE", KEYS[1], ARGV[2])
+return {ARGV[1], "0"}
+`)
+
+// ClaimClientMsgID associa client_msg_id a messageID; num reenvio retorna a
+// mensagem associada na primeira tentativa
+func (cw *ClientWrapper) ClaimClientMsgID(ctx context.Context, roomID, user, clientMsgID, messageID string) (SentMessage, error) {
+	res, err := claimSentScript.Run(ctx, cw.Client, []string{sentKey(roomID, user, clientMsgID)}, messageID, int(sentTTL.Seconds())).StringSlice()
+	if err != nil {
+		return SentMessage{}, err
+	}
+	return SentMessage{MessageID: res[0], Published: res[1] == "1"}, nil
+}
+
+// SetClientMsgID troca a mensagem associada a client_msg_id, ao marcá-la como
+// publicada ou quando a primeira tentativa não chegou a gravá-la
+func (cw *ClientWrapper) SetClientMsgID(ctx context.Context, roomID, user, clientMsgID string, sent SentMessage) error {
+	published := "0"
+	if sent.Published {
+		published = "1"
+	}
+	key := sentKey(roomID, user, clientMsgID)
+	_, err := cw.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "id", sent.MessageID, "published", published)
+		pipe.Expire(ctx, key, sentTTL)
+		return nil
+	})
+	return err
+}
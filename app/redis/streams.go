@@ -0,0 +1,242 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Transportes disponíveis para a entrega entre instâncias
+const (
+	TransportPubSub  = "pubsub"
+	TransportStreams = "streams"
+)
+
+// streams compartilhados por todas as salas; a sala vai no campo channel
+const (
+	messagesStream = "stream:chat"
+	eventsStream   = "stream:events"
+)
+
+// intervalo máximo de cada XREAD bloqueante e espera antes de tentar de novo
+// depois de um erro
+const (
+	streamBlock      = 5 * time.Second
+	streamRetryDelay = time.Second
+	streamReadCount  = 100
+)
+
+// Transport é a entrega de mensagens e eventos entre as instâncias
+type Transport interface {
+	Publisher
+	Subscriber
+}
+
+// StreamTransport entrega mensagens e eventos por Redis Streams em vez de
+// pub/sub. Cada instância grava no Redis o ID da última entrada lida, com o
+// nome estável da configuração; depois de uma queda de conexão ou de um
+// restart ela continua a partir dele, sem perder o que foi publicado nesse
+// intervalo (enquanto ainda estiver dentro de maxLen).
+type StreamTransport struct {
+	client   *redis.Client
+	logger   logger.Logger
+	maxLen   int64
+	instance string
+}
+
+func NewStreamTransport(client *redis.Client, logger logger.Logger, maxLen int64, instance string) *StreamTransport {
+	return &StreamTransport{client: client, logger: logger, maxLen: maxLen, instance: instance}
+}
+
+// cursorKey guarda o último ID do stream lido pela instância
+func (t *StreamTransport) cursorKey(stream string) string {
+	return "stream:cursor:" + t.instance + ":" + stream
+}
+
+func (t *StreamTransport) PublishMessage(ctx context.Context, channel string, msg dto.Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return t.add(ctx, messagesStream, channel, payload)
+}
+
+func (t *StreamTransport) PublishEvent(ctx context.Context, channel string, event dto.RoomEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return t.add(ctx, eventsStream, channel, payload)
+}
+
+// add grava a entrada cortando o stream em aproximadamente maxLen entradas
+func (t *StreamTransport) add(ctx context.Context, stream, channel string, payload []byte) error {
+	return t.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: t.maxLen,
+		Approx: true,
+		Values: map[string]any{"channel": channel, "payload": payload},
+	}).Err()
+}
+
+func (t *StreamTransport) SubscribeAllRooms(ctx context.Context, handler func(dto.Message)) {
+	t.logger.InfoF("Iniciando leitura do stream %s", messagesStream)
+	t.read(ctx, messagesStream, func(payload string) {
+		var msg dto.Message
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			return
+		}
+		handler(msg)
+	})
+}
+
+func (t *StreamTransport) SubscribeRoomEvents(ctx context.Context, handler func(dto.RoomEvent)) {
+	t.read(ctx, eventsStream, func(payload string) {
+		var event dto.RoomEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			return
+		}
+		handler(event)
+	})
+}
+
+// read entrega as entradas do stream em ordem a partir do último ID lido por
+// esta instância, gravando o cursor a cada lote; erros de conexão só atrasam
+// a leitura
+func (t *StreamTransport) read(ctx context.Context, stream string, handle func(string)) {
+	lastID, ok := t.lastID(ctx, stream)
+	if !ok {
+		return
+	}
+
+	for {
+		streams, err := t.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{stream, lastID},
+			Count:   streamReadCount,
+			Block:   streamBlock,
+		}).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			t.logger.InfoF("Erro ao ler o stream %s a partir de %s: %v", stream, lastID, err)
+			if !sleepCtx(ctx, streamRetryDelay) {
+				return
+			}
+			t.checkGap(ctx, stream, lastID)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, entry := range s.Messages {
+				lastID = entry.ID
+				if payload, ok := entry.Values["payload"].(string); ok {
+					handle(payload)
+				}
+			}
+		}
+		if err := t.client.Set(ctx, t.cursorKey(stream), lastID, 0).Err(); err != nil {
+			t.logger.InfoF("Erro ao gravar o cursor do stream %s: %v", stream, err)
+		}
+	}
+}
+
+// lastID resolve o ponto de partida da leitura: o cursor gravado pela
+// instância ou, na primeira vez que ela sobe, a entrada mais recente, que já
+// é gravada como cursor. Usar "$" a cada XREAD perderia o que fosse publicado
+// entre duas leituras.
+func (t *StreamTransport) lastID(ctx context.Context, stream string) (string, bool) {
+	for {
+		id, err := t.startID(ctx, stream)
+		if err == nil {
+			return id, true
+		}
+		if ctx.Err() != nil {
+			return "", false
+		}
+		t.logger.InfoF("Erro ao consultar o stream %s: %v", stream, err)
+		if !sleepCtx(ctx, streamRetryDelay) {
+			return "", false
+		}
+	}
+}
+
+func (t *StreamTransport) startID(ctx context.Context, stream string) (string, error) {
+	key := t.cursorKey(stream)
+	id, err := t.client.Get(ctx, key).Result()
+	if err == nil {
+		t.logger.InfoF("Retomando o stream %s a partir de %s", stream, id)
+		t.checkGap(ctx, stream, id)
+		return id, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	entries, err := t.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	id = "0-0"
+	if len(entries) > 0 {
+		id = entries[0].ID
+	}
+	return id, t.client.Set(ctx, key, id, 0).Err()
+}
+
+// checkGap avisa quando o stream já descartou (pelo corte em maxLen) entradas
+// posteriores ao cursor: elas não serão entregues por esta instância, e os
+// clientes só as recuperam pelo histórico
+func (t *StreamTransport) checkGap(ctx context.Context, stream, lastID string) {
+	info, err := t.client.XInfoStream(ctx, stream).Result()
+	if errors.Is(err, redis.Nil) || (err != nil && strings.Contains(err.Error(), "no such key")) {
+		return
+	}
+	if err != nil {
+		t.logger.InfoF("Erro ao consultar o stream %s: %v", stream, err)
+		return
+	}
+	if compareStreamIDs(info.MaxDeletedEntryID, lastID) > 0 {
+		t.logger.ErrorF("Lacuna no stream %s: entradas até %s foram descartadas depois do cursor %s (primeira mantida: %s)",
+			stream, info.MaxDeletedEntryID, lastID, info.FirstEntry.ID)
+	}
+}
+
+// compareStreamIDs compara dois IDs de stream no formato "ms-seq"
+func compareStreamIDs(a, b string) int {
+	am, as := parseStreamID(a)
+	bm, bs := parseStreamID(b)
+	if am != bm {
+		return cmp.Compare(am, bm)
+	}
+	return cmp.Compare(as, bs)
+}
+
+func parseStreamID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	m, _ := strconv.ParseUint(ms, 10, 64)
+	s, _ := strconv.ParseUint(seq, 10, 64)
+	return m, s
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brunobotter/chat-websocket/dto"
+)
+
+// As respostas de uma thread ficam fora do histórico da sala, em
+// thread:<sala>:<mensagem>, com IDs "<mensagem>.<n>" de um contador próprio;
+// assim não entram na sequência da sala nem nas contagens de não lidas.
+func threadKey(roomID, parentID string) string {
+	return "thread:" + roomID + ":" + parentID
+}
+
+// messageListKey indica a lista onde está a mensagem: a da thread para
+// respostas e o histórico da sala para as demais
+func messageListKey(roomID, id string) string {
+	if parentID, _, ok := strings.Cut(id, "."); ok {
+		return threadKey(roomID, parentID)
+	}
+	return "chat:" + roomID
+}
+
+// replySeq retorna o n de um ID de resposta "<mensagem>.<n>"
+func replySeq(id string) (int64, bool) {
+	_, n, ok := strings.Cut(id, ".")
+	if !ok {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(n, 10, 64)
+	return seq, err == nil
+}
+
+// NextReplyID gera o ID da próxima resposta da thread
+func (cw *ClientWrapper) NextReplyID(ctx context.Context, roomID, parentID string) (string, error) {
+	seq, err := cw.Client.Incr(ctx, threadKey(roomID, parentID)+":seq").Result()
+	if err != nil {
+		return "", err
+	}
+	return parentID + "." + strconv.FormatInt(seq, 10), nil
+}
+
+// SaveThreadReply grava a resposta na thread, que expira junto com o histórico
+// da sala, e retorna o total de respostas
+func (cw *ClientWrapper) SaveThreadReply(ctx context.Context, roomID string, msg dto.Message, retention Retention) (int64, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	key := threadKey(roomID, msg.ParentID)
+	pipe := cw.Client.TxPipeline()
+	count := pipe.LPush(ctx, key, payload)
+	retention.expire(ctx, pipe, key)
+	retention.expire(ctx, pipe, key+":seq")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// GetThread retorna até limit respostas da thread anteriores a before (todas
+// as mais recentes quando before é vazio), da mais antiga para a mais nova,
+// e se ainda há respostas mais antigas
+func (cw *ClientWrapper) GetThread(ctx context.Context, roomID, parentID, before string, limit int) ([]dto.Message, bool, error) {
+	var cursor int64
+	if before != "" {
+		seq, ok := replySeq(before)
+		if !ok || !strings.HasPrefix(before, parentID+".") {
+			return nil, false, ErrInvalidMessageID
+		}
+		cursor = seq
+	}
+
+	vals, err := cw.Client.LRange(ctx, threadKey(roomID, parentID), 0, -1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// a lista vai da resposta mais nova para a mais antiga
+	replies := make([]dto.Message, 0, limit)
+	more := false
+	for _, val := range vals {
+		var msg dto.Message
+		if err := json.Unmarshal([]byte(val), &msg); err != nil {
+			continue
+		}
+		if seq, ok := replySeq(msg.ID); !ok || (cursor > 0 && seq >= cursor) {
+			continue
+		}
+		if len(replies) == limit {
+			more = true
+			break
+		}
+		replies = append(replies, msg)
+	}
+
+	slices.Reverse(replies)
+	replies, err = cw.withAggregates(ctx, roomID, replies)
+	return replies, more, err
+}
@@ -0,0 +1,62 @@
+package room
+
+import "slices"
+
+type Role string
+
+const (
+	RoleOwner     Role = "owner"
+	RoleModerator Role = "moderator"
+	RoleMember    Role = "member"
+	RoleReadOnly  Role = "readonly"
+	RoleNone      Role = ""
+)
+
+type Permission string
+
+const (
+	PermSend            Permission = "send"
+	PermReadHistory     Permission = "read_history"
+	PermDeleteOthers    Permission = "delete_others"
+	PermInvite          Permission = "invite"
+	PermKick            Permission = "kick"
+	PermChangeTopic     Permission = "change_topic"
+	PermManageRoles     Permission = "manage_roles"
+	PermManageRetention Permission = "manage_retention"
+)
+
+// matriz de permissões por papel
+var permissions = map[Role][]Permission{
+	RoleOwner:     {PermSend, PermReadHistory, PermDeleteOthers, PermInvite, PermKick, PermChangeTopic, PermManageRoles, PermManageRetention},
+	RoleModerator: {PermSend, PermReadHistory, PermDeleteOthers, PermInvite, PermKick, PermChangeTopic},
+	RoleMember:    {PermSend, PermReadHistory},
+	RoleReadOnly:  {PermReadHistory},
+}
+
+// hierarquia usada para impedir que um papel aja sobre outro igual ou superior
+var rank = map[Role]int{
+	RoleOwner:     3,
+	RoleModerator: 2,
+	RoleMember:    1,
+	RoleReadOnly:  1,
+}
+
+func (r Role) Can(p Permission) bool {
+	return slices.Contains(permissions[r], p)
+}
+
+// Outranks indica se r pode expulsar ou alterar o papel de other
+func (r Role) Outranks(other Role) bool {
+	return rank[r] > rank[other]
+}
+
+// CanAssign indica se r pode trocar o papel current de um membro por next:
+// precisa gerenciar papéis e estar acima dos dois
+func (r Role) CanAssign(current, next Role) bool {
+	return r.Can(PermManageRoles) && r.Outranks(current) && r.Outranks(next)
+}
+
+func (r Role) Valid() bool {
+	_, ok := permissions[r]
+	return ok
+}
@@ -0,0 +1,91 @@
+package room
+
+import "testing"
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role Role
+		perm Permission
+		want bool
+	}{
+		{RoleOwner, PermManageRoles, true},
+		{RoleOwner, PermManageRetention, true},
+		{RoleModerator, PermKick, true},
+		{RoleModerator, PermManageRoles, false},
+		{RoleModerator, PermManageRetention, false},
+		{RoleMember, PermSend, true},
+		{RoleMember, PermKick, false},
+		{RoleMember, PermDeleteOthers, false},
+		{RoleReadOnly, PermReadHistory, true},
+		{RoleReadOnly, PermSend, false},
+		{RoleNone, PermReadHistory, false},
+		{Role("admin"), PermSend, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.perm); got != tt.want {
+			t.Errorf("%q.Can(%q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestRoleOutranks(t *testing.T) {
+	tests := []struct {
+		role, other Role
+		want        bool
+	}{
+		{RoleOwner, RoleModerator, true},
+		{RoleOwner, RoleOwner, false},
+		{RoleModerator, RoleMember, true},
+		{RoleModerator, RoleReadOnly, true},
+		{RoleModerator, RoleModerator, false},
+		{RoleModerator, RoleOwner, false},
+		{RoleMember, RoleReadOnly, false},
+		{RoleReadOnly, RoleMember, false},
+		{RoleMember, RoleNone, true},
+		{RoleNone, RoleNone, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Outranks(tt.other); got != tt.want {
+			t.Errorf("%q.Outranks(%q) = %v, want %v", tt.role, tt.other, got, tt.want)
+		}
+	}
+}
+
+func TestRoleCanAssign(t *testing.T) {
+	tests := []struct {
+		name                string
+		role, current, next Role
+		want                bool
+	}{
+		{"owner promotes member to moderator", RoleOwner, RoleMember, RoleModerator, true},
+		{"owner demotes moderator", RoleOwner, RoleModerator, RoleReadOnly, true},
+		{"owner grants room to outsider", RoleOwner, RoleNone, RoleMember, true},
+		{"owner cannot create another owner", RoleOwner, RoleMember, RoleOwner, false},
+		{"owner cannot demote another owner", RoleOwner, RoleOwner, RoleMember, false},
+		{"moderator cannot manage roles", RoleModerator, RoleMember, RoleReadOnly, false},
+		{"member cannot manage roles", RoleMember, RoleReadOnly, RoleReadOnly, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.role.CanAssign(tt.current, tt.next); got != tt.want {
+				t.Errorf("%q.CanAssign(%q, %q) = %v, want %v", tt.role, tt.current, tt.next, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleValid(t *testing.T) {
+	for _, role := range []Role{RoleOwner, RoleModerator, RoleMember, RoleReadOnly} {
+		if !role.Valid() {
+			t.Errorf("%q.Valid() = false, want true", role)
+		}
+	}
+	for _, role := range []Role{RoleNone, "admin", "Owner"} {
+		if role.Valid() {
+			t.Errorf("%q.Valid() = true, want false", role)
+		}
+	}
+}
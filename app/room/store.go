@@ -0,0 +1,223 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+const kickChannel = "room:kicks"
+
+var (
+	ErrNotFound      = errors.New("room not found")
+	ErrAlreadyExists = errors.New("room already exists")
+)
+
+// Interface para salas, papéis e expulsões
+type Store interface {
+	CreateRoom(ctx context.Context, room dto.Room) error
+	GetRoom(ctx context.Context, roomID string) (*dto.Room, error)
+	SetTopic(ctx context.Context, roomID, topic string) error
+	SetRetention(ctx context.Context, roomID string, retention *dto.Retention) error
+	SetRole(ctx context.Context, roomID, user string, role Role) error
+	RemoveMember(ctx context.Context, roomID, user string) error
+	Readmit(ctx context.Context, roomID, user string) error
+	Members(ctx context.Context, roomID string) (map[string]Role, error)
+	RoleOf(ctx context.Context, roomID, user string, granted []string) (Role, error)
+	PublishKick(ctx context.Context, kick dto.Kick) error
+	SubscribeKicks(ctx context.Context, handler func(dto.Kick))
+}
+
+// RedisStore guarda os dados da sala em room:<id>, os papéis em room:<id>:roles
+// e quem foi retirado da sala em room:<id>:removed
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func roomKey(roomID string) string {
+	return "room:" + roomID
+}
+
+func rolesKey(roomID string) string {
+	return "room:" + roomID + ":roles"
+}
+
+// removedKey marca quem foi expulso ou teve a sala retirada: o access token
+// ainda em uso lista a sala até o próximo refresh e não pode devolver o acesso
+func removedKey(roomID string) string {
+	return "room:" + roomID + ":removed"
+}
+
+// createRoomScript só grava o registro de uma sala sem vestígios: salas
+// antigas (default, vip...) não têm registro, mas já têm histórico ou papéis
+var createRoomScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2], KEYS[3], KEYS[4]) > 0 then
+	return 0
+end
+return redis.call("SETNX", KEYS[1], ARGV[1])
+`)
+
+func (s *RedisStore) CreateRoom(ctx context.Context, r dto.Room) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{roomKey(r.ID), rolesKey(r.ID), "chat:" + r.ID, "chat:" + r.ID + ":seq"}
+	created, err := createRoomScript.Run(ctx, s.client, keys, payload).Int()
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+func (s *RedisStore) GetRoom(ctx context.Context, roomID string) (*dto.Room, error) {
+	val, err := s.client.Get(ctx, roomKey(roomID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r dto.Room
+	if err := json.Unmarshal([]byte(val), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *RedisStore) SetTopic(ctx context.Context, roomID, topic string) error {
+	return s.updateRoom(ctx, roomID, func(r *dto.Room) { r.Topic = topic })
+}
+
+// SetRetention troca a política de histórico da sala; nil volta ao padrão da configuração
+func (s *RedisStore) SetRetention(ctx context.Context, roomID string, retention *dto.Retention) error {
+	return s.updateRoom(ctx, roomID, func(r *dto.Room) { r.Retention = retention })
+}
+
+func (s *RedisStore) updateRoom(ctx context.Context, roomID string, update func(*dto.Room)) error {
+	r, err := s.GetRoom(ctx, roomID)
+	if errors.Is(err, ErrNotFound) {
+		// salas antigas (default, vip...) não têm registro; é criado no primeiro ajuste
+		r = &dto.Room{ID: roomID, DefaultRole: string(RoleMember)}
+	} else if err != nil {
+		return err
+	}
+	update(r)
+
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, roomKey(roomID), payload, 0).Err()
+}
+
+func (s *RedisStore) SetRole(ctx context.Context, roomID, user string, role Role) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, rolesKey(roomID), user, string(role))
+		pipe.SRem(ctx, removedKey(roomID), user)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) RemoveMember(ctx context.Context, roomID, user string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, rolesKey(roomID), user)
+		pipe.SAdd(ctx, removedKey(roomID), user)
+		return nil
+	})
+	return err
+}
+
+// Readmit desfaz a marca de retirada quando o usuário volta a receber a sala
+func (s *RedisStore) Readmit(ctx context.Context, roomID, user string) error {
+	return s.client.SRem(ctx, removedKey(roomID), user).Err()
+}
+
+func (s *RedisStore) Members(ctx context.Context, roomID string) (map[string]Role, error) {
+	vals, err := s.client.HGetAll(ctx, rolesKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]Role, len(vals))
+	for user, role := range vals {
+		members[user] = Role(role)
+	}
+	return members, nil
+}
+
+// RoleOf resolve o papel do usuário na sala: o papel explícito, se houver,
+// ou o papel padrão da sala quando ela consta nas salas concedidas ao usuário
+// e ele não foi retirado dela depois da emissão do token
+func (s *RedisStore) RoleOf(ctx context.Context, roomID, user string, granted []string) (Role, error) {
+	pipe := s.client.Pipeline()
+	explicit := pipe.HGet(ctx, rolesKey(roomID), user)
+	removed := pipe.SIsMember(ctx, removedKey(roomID), user)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return RoleNone, err
+	}
+	if role, err := explicit.Result(); err == nil {
+		return Role(role), nil
+	}
+
+	if removed.Val() || !slices.Contains(granted, roomID) {
+		return RoleNone, nil
+	}
+
+	r, err := s.GetRoom(ctx, roomID)
+	if errors.Is(err, ErrNotFound) {
+		return RoleMember, nil
+	}
+	if err != nil {
+		return RoleNone, err
+	}
+	if Role(r.DefaultRole).Valid() {
+		return Role(r.DefaultRole), nil
+	}
+	return RoleMember, nil
+}
+
+func (s *RedisStore) PublishKick(ctx context.Context, kick dto.Kick) error {
+	payload, err := json.Marshal(kick)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, kickChannel, payload).Err()
+}
+
+func (s *RedisStore) SubscribeKicks(ctx context.Context, handler func(dto.Kick)) {
+	pubsub := s.client.Subscribe(ctx, kickChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var kick dto.Kick
+			if err := json.Unmarshal([]byte(msg.Payload), &kick); err != nil {
+				continue
+			}
+			handler(kick)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+)
+
+// MemoryStore guarda as contas em memória (desenvolvimento e instância única)
+type MemoryStore struct {
+	mu          sync.RWMutex
+	users       map[string]dto.User
+	resetTokens map[string]resetToken
+}
+
+type resetToken struct {
+	username  string
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[string]dto.User),
+		resetTokens: make(map[string]resetToken),
+	}
+}
+
+func (s *MemoryStore) GetUser(ctx context.Context, username string) (*dto.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	u.Rooms = append([]string(nil), u.Rooms...)
+	return &u, nil
+}
+
+func (s *MemoryStore) SaveUser(ctx context.Context, u dto.User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	u.Rooms = append([]string(nil), u.Rooms...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Username] = u
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, u dto.User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	u.Rooms = append([]string(nil), u.Rooms...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[u.Username]; ok {
+		return ErrAlreadyExists
+	}
+	s.users[u.Username] = u
+	return nil
+}
+
+func (s *MemoryStore) UpdatePassword(ctx context.Context, username, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	s.users[username] = u
+	return nil
+}
+
+func (s *MemoryStore) SetAdmin(ctx context.Context, username string, admin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Admin = admin
+	s.users[username] = u
+	return nil
+}
+
+func (s *MemoryStore) VerifyPassword(ctx context.Context, username, password string) (*dto.User, error) {
+	u, err := s.GetUser(ctx, username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err := checkPassword(u, password); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) AllowedRooms(ctx context.Context, username string) ([]string, error) {
+	u, err := s.GetUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return u.Rooms, nil
+}
+
+func (s *MemoryStore) GrantRoom(ctx context.Context, username, room string) error {
+	return s.updateRooms(username, func(rooms []string) []string { return withRoom(rooms, room) })
+}
+
+func (s *MemoryStore) RemoveRoom(ctx context.Context, username, room string) error {
+	return s.updateRooms(username, func(rooms []string) []string { return withoutRoom(rooms, room) })
+}
+
+func (s *MemoryStore) RoomGranted(ctx context.Context, room string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if slices.Contains(u.Rooms, room) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) updateRooms(username string, update func([]string) []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Rooms = update(append([]string(nil), u.Rooms...))
+	s.users[username] = u
+	return nil
+}
+
+func (s *MemoryStore) SaveResetToken(ctx context.Context, token, username string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resetTokens[token] = resetToken{username: username, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) ConsumeResetToken(ctx context.Context, token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.resetTokens[token]
+	delete(s.resetTokens, token)
+	if !ok || time.Now().After(rt.expiresAt) {
+		return "", ErrInvalidResetToken
+	}
+	return rt.username, nil
+}
@@ -0,0 +1,207 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore guarda as contas no Redis, compartilhadas entre as instâncias
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func resetTokenKey(token string) string {
+	return "password_reset:" + token
+}
+
+func (s *RedisStore) GetUser(ctx context.Context, username string) (*dto.User, error) {
+	val, err := s.client.Get(ctx, userKey(username)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var u dto.User
+	if err := json.Unmarshal([]byte(val), &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *RedisStore) SaveUser(ctx context.Context, u dto.User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+
+	payload, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, userKey(u.Username), payload, 0).Err()
+}
+
+func (s *RedisStore) CreateUser(ctx context.Context, u dto.User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+
+	payload, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	// SETNX garante que duas instâncias não criem a mesma conta
+	created, err := s.client.SetNX(ctx, userKey(u.Username), payload, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+func (s *RedisStore) UpdatePassword(ctx context.Context, username, passwordHash string) error {
+	return s.updateUser(ctx, username, func(u *dto.User) { u.PasswordHash = passwordHash })
+}
+
+func (s *RedisStore) SetAdmin(ctx context.Context, username string, admin bool) error {
+	return s.updateUser(ctx, username, func(u *dto.User) { u.Admin = admin })
+}
+
+func (s *RedisStore) VerifyPassword(ctx context.Context, username, password string) (*dto.User, error) {
+	u, err := s.GetUser(ctx, username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err := checkPassword(u, password); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *RedisStore) AllowedRooms(ctx context.Context, username string) ([]string, error) {
+	u, err := s.GetUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return u.Rooms, nil
+}
+
+func (s *RedisStore) GrantRoom(ctx context.Context, username, room string) error {
+	return s.updateRooms(ctx, username, func(rooms []string) []string { return withRoom(rooms, room) })
+}
+
+func (s *RedisStore) RemoveRoom(ctx context.Context, username, room string) error {
+	return s.updateRooms(ctx, username, func(rooms []string) []string { return withoutRoom(rooms, room) })
+}
+
+// RoomGranted indica se alguma conta tem a sala concedida; percorre as contas
+// com SCAN, então só serve para operações raras como a criação de salas
+func (s *RedisStore) RoomGranted(ctx context.Context, room string) (bool, error) {
+	iter := s.client.Scan(ctx, 0, userKey("*"), 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < 100 {
+			continue
+		}
+		granted, err := s.anyGranted(ctx, keys, room)
+		if err != nil || granted {
+			return granted, err
+		}
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return false, err
+	}
+	return s.anyGranted(ctx, keys, room)
+}
+
+func (s *RedisStore) anyGranted(ctx context.Context, keys []string, room string) (bool, error) {
+	if len(keys) == 0 {
+		return false, nil
+	}
+	vals, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, val := range vals {
+		raw, ok := val.(string)
+		if !ok {
+			continue
+		}
+		var u dto.User
+		if err := json.Unmarshal([]byte(raw), &u); err == nil && slices.Contains(u.Rooms, room) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *RedisStore) updateRooms(ctx context.Context, username string, update func([]string) []string) error {
+	return s.updateUser(ctx, username, func(u *dto.User) { u.Rooms = update(u.Rooms) })
+}
+
+// updateUser usa WATCH para que alterações concorrentes de outras instâncias não se percam
+func (s *RedisStore) updateUser(ctx context.Context, username string, update func(*dto.User)) error {
+	key := userKey(username)
+	return s.client.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var u dto.User
+		if err := json.Unmarshal([]byte(val), &u); err != nil {
+			return err
+		}
+		update(&u)
+
+		payload, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, payload, 0)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (s *RedisStore) SaveResetToken(ctx context.Context, token, username string, ttl time.Duration) error {
+	return s.client.Set(ctx, resetTokenKey(token), username, ttl).Err()
+}
+
+// ConsumeResetToken usa GETDEL para que o token só possa ser usado uma vez
+func (s *RedisStore) ConsumeResetToken(ctx context.Context, token string) (string, error) {
+	username, err := s.client.GetDel(ctx, resetTokenKey(token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidResetToken
+	}
+	if err != nil {
+		return "", err
+	}
+	return username, nil
+}
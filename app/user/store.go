@@ -0,0 +1,115 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrNotFound           = errors.New("user not found")
+	ErrAlreadyExists      = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+)
+
+// Interface para contas de usuário
+type UserStore interface {
+	GetUser(ctx context.Context, username string) (*dto.User, error)
+	SaveUser(ctx context.Context, user dto.User) error
+	CreateUser(ctx context.Context, user dto.User) error
+	UpdatePassword(ctx context.Context, username, passwordHash string) error
+	SetAdmin(ctx context.Context, username string, admin bool) error
+	VerifyPassword(ctx context.Context, username, password string) (*dto.User, error)
+	AllowedRooms(ctx context.Context, username string) ([]string, error)
+	GrantRoom(ctx context.Context, username, room string) error
+	RemoveRoom(ctx context.Context, username, room string) error
+	RoomGranted(ctx context.Context, room string) (bool, error)
+	SaveResetToken(ctx context.Context, token, username string, ttl time.Duration) error
+	ConsumeResetToken(ctx context.Context, token string) (string, error)
+}
+
+// hash usado quando o usuário não existe, para que o tempo de resposta
+// do login não revele quais contas estão cadastradas
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword gera o hash bcrypt da senha
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword compara a senha com o hash do usuário (nil quando o usuário não existe)
+func checkPassword(u *dto.User, password string) error {
+	if u == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// withRoom e withoutRoom aplicam uma concessão ou remoção de sala sem duplicar entradas
+func withRoom(rooms []string, room string) []string {
+	if slices.Contains(rooms, room) {
+		return rooms
+	}
+	return append(rooms, room)
+}
+
+func withoutRoom(rooms []string, room string) []string {
+	return slices.DeleteFunc(rooms, func(r string) bool { return r == room })
+}
+
+// NewResetToken gera um token aleatório para redefinição de senha
+func NewResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Seed cria as contas no formato "usuario:senha" que ainda não existirem.
+// A permissão de administrador segue a lista admins também nas contas que já
+// existiam, sem mexer em senha nem salas.
+func Seed(ctx context.Context, store UserStore, entries []string, rooms []string, admins []string) error {
+	for _, entry := range entries {
+		name, password, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || password == "" {
+			continue
+		}
+
+		hash, err := HashPassword(password)
+		if err != nil {
+			return err
+		}
+
+		admin := slices.Contains(admins, name)
+		err = store.CreateUser(ctx, dto.User{
+			Username:     name,
+			PasswordHash: hash,
+			Rooms:        rooms,
+			Admin:        admin,
+		})
+		if errors.Is(err, ErrAlreadyExists) {
+			err = store.SetAdmin(ctx, name, admin)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package user
+
+import (
+	"errors"
+	"regexp"
+	"unicode"
+)
+
+var (
+	ErrInvalidUsername = errors.New("username must have 3 to 32 characters: lowercase letters, digits, '.', '_' or '-'")
+	ErrWeakPassword    = errors.New("password must have 8 to 72 characters with at least one letter and one digit")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,31}$`)
+
+// ValidateUsername aplica as regras de formato do nome de usuário
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// ValidatePassword aplica as regras mínimas de força da senha.
+// O limite de 72 bytes vem do bcrypt, que ignora o que passar disso.
+func ValidatePassword(password string) error {
+	if len(password) < 8 || len(password) > 72 {
+		return ErrWeakPassword
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return ErrWeakPassword
+	}
+	return nil
+}
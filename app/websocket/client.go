@@ -3,170 +3,547 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/dto"
 	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+// bearerSubprotocol permite que navegadores, que não conseguem definir o header
+// Authorization no new WebSocket(), enviem o token como subprotocolo:
+// new WebSocket(url, ["bearer", token])
+const bearerSubprotocol = "bearer"
+
+func newUpgrader(origins *OriginPolicy) websocket.Upgrader {
+	return websocket.Upgrader{
+		CheckOrigin:  origins.Check,
+		Subprotocols: []string{bearerSubprotocol},
+	}
 }
 
+// Client é uma conexão WebSocket, que pode participar de várias salas. RoomID
+// é a sala da conexão, usada nos frames que não informam room.
 type Client struct {
 	Conn   *websocket.Conn
 	Send   chan []byte
 	Hub    *Hub
 	RoomID string
 	User   string
-}
+	Claims *auth.Claims
 
-func HandleConnections(hub *Hub, w http.ResponseWriter, r *http.Request, messageStore redis.MessageStore, publisher redis.Publisher) {
-	ws, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
+	mu    sync.RWMutex
+	rooms map[string]bool
 
-	// 1. Pegando token do header Authorization
-	tokenStr := r.Header.Get("Authorization")
-	if tokenStr == "" {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		ws.Close()
-		return
-	}
+	sendMu     sync.Mutex
+	closed     bool
+	closeFrame []byte
+}
 
-	if len(tokenStr) > 7 && tokenStr[:7] == "Bearer " {
-		tokenStr = tokenStr[7:]
-	}
+// Códigos de fechamento (faixa 4000-4999, livre para aplicações): 4401 quando
+// o token da sessão expira ou é revogado e 4403 quando o usuário perde o
+// acesso à última sala da conexão; o motivo vai no frame
+const (
+	CloseUnauthorized = 4401
+	CloseForbidden    = 4403
+)
 
-	claims, err := auth.ValidateAccessToken(tokenStr)
+// HandleConnections faz o upgrade de uma requisição já autenticada e autorizada
+// pelos middlewares (JWTMiddleware e RoomAccess) e mantém a conexão na sala
+func HandleConnections(hub *Hub, w http.ResponseWriter, r *http.Request, claims *auth.Claims, roomID string, role room.Role) {
+	ws, err := hub.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		ws.Close()
 		return
 	}
 
-	// 2. Sala desejada
-	room := r.URL.Query().Get("room")
-	if room == "" {
-		room = "default"
-	}
-	// 3. Verifica se usuário tem acesso à sala
-	authorized := false
-	for _, r := range claims.Rooms {
-		if r == room {
-			authorized = true
-			break
-		}
-	}
-	if !authorized {
-		http.Error(w, "forbidden", http.StatusForbidden)
-		ws.Close()
-		return
-	}
 	client := &Client{
 		Conn:   ws,
 		Send:   make(chan []byte, 256),
 		Hub:    hub,
-		RoomID: room,
+		RoomID: roomID,
 		User:   claims.User,
+		Claims: claims,
+		rooms:  map[string]bool{roomID: true},
 	}
 	hub.Register <- client
 
-	// 5. Envia histórico
-	if history, err := messageStore.GetMessages(r.Context(), room, 50); err == nil {
-		for _, msg := range history {
-			client.Send <- []byte(msg.Content)
-		}
+	// Envia histórico em um único evento, separado das mensagens ao vivo; na
+	// reconexão (?since=<id>) só o que o cliente ainda não tem
+	if role.Can(room.PermReadHistory) {
+		client.sendHistory(r.Context(), "", roomID, r.URL.Query().Get("since"))
 	}
 
 	// Mensagem de boas-vindas
-	msg, _ := json.Marshal(map[string]string{"msg": "connected to " + room})
-	client.Send <- msg
+	client.sendEvent(dto.EventSystem, "", roomID, dto.SystemEvent{
+		Event:   "connected",
+		Message: "connected to " + roomID,
+		Role:    string(role),
+	})
+	client.sendPresence(r.Context(), "", roomID)
 
 	go client.writePump()
-	client.readPump(publisher, messageStore)
+	client.readPump()
 }
 
-func (c *Client) readPump(publisher redis.Publisher, messageStore redis.MessageStore) {
+// IsUpgradeRequest indica se a requisição pede upgrade para WebSocket
+func IsUpgradeRequest(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// SubprotocolToken retorna o token enviado como subprotocolo ("bearer", <token>)
+func SubprotocolToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == bearerSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// readPump lê os frames do cliente; sem pong dentro do PongWait o deadline de
+// leitura expira e a conexão é desregistrada do Hub
+func (c *Client) readPump() {
 	defer func() {
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
 
+	cfg := c.Hub.conn
+	c.Conn.SetReadLimit(cfg.MaxMessageSize)
+	_ = c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	})
+
 	for {
-		_, msgBytes, err := c.Conn.ReadMessage()
+		_, frame, err := c.Conn.ReadMessage()
 		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, CloseUnauthorized, CloseForbidden) {
+				c.Hub.logger.InfoF("Conexão de %s encerrada: %v", c.User, err)
+			}
 			break
 		}
-		var incoming dto.Incoming
-		if err := json.Unmarshal(msgBytes, &incoming); err != nil {
+		env, err := decodeEnvelope(frame)
+		if err != nil {
+			c.sendError("", "", ErrCodeBadRequest, "invalid frame")
 			continue
 		}
-
-		msg := dto.Message{
-			User:      c.User,
-			Content:   incoming.Content,
-			Timestamp: time.Now(),
-			RoomID:    c.RoomID,
-			Target:    incoming.Target,
+		if env.Version != dto.ProtocolVersion {
+			c.sendError(env.ID, env.Room, ErrCodeUnsupportedVersion, "unsupported protocol version")
+			continue
+		}
+		if env.Room == "" {
+			env.Room = c.RoomID
 		}
 
-		ctx := context.Background()
-		_ = publisher.PublishMessage(ctx, "chat:"+c.RoomID, msg)
-		_ = messageStore.SaveMessage(ctx, c.RoomID, msg, 50)
+		switch env.Type {
+		case dto.EventMessage:
+			c.handleMessage(env)
+		case dto.EventResume:
+			c.handleResume(env)
+		case dto.EventHistory:
+			c.handleHistory(env)
+		case dto.EventJoin:
+			c.handleJoin(env)
+		case dto.EventLeave:
+			c.handleLeave(env)
+		case dto.EventTypingStart, dto.EventTypingStop:
+			c.handleTyping(env)
+		case dto.EventRead:
+			c.handleRead(env)
+		case dto.EventEdit:
+			c.handleEdit(env)
+		case dto.EventDelete:
+			c.handleDelete(env)
+		case dto.EventReact, dto.EventUnreact:
+			c.handleReaction(env)
+		default:
+			c.sendError(env.ID, env.Room, ErrCodeUnsupportedType, "unsupported event type: "+string(env.Type))
+		}
 	}
 }
 
-func (c *Client) writePump() {
-	defer c.Conn.Close()
-	for msg := range c.Send {
-		err := c.Conn.WriteMessage(websocket.TextMessage, msg)
+// handleMessage grava e publica a mensagem enviada pelo cliente em uma das
+// salas em que ele entrou
+func (c *Client) handleMessage(env dto.Envelope) {
+	var incoming dto.Incoming
+	if err := json.Unmarshal(env.Payload, &incoming); err != nil || incoming.Content == "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid message payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermSend) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot send messages in "+env.Room)
+		return
+	}
+
+	clientMsgID := incoming.ClientMsgID
+	if clientMsgID == "" {
+		clientMsgID = env.ID
+	}
+	if incoming.ReplyTo != "" {
+		c.handleReply(ctx, env, incoming, clientMsgID)
+		return
+	}
+
+	id, err := c.Hub.ChatStore.NextMessageID(ctx, env.Room)
+	if err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+
+	msg := dto.Message{
+		ID:          id,
+		ClientMsgID: clientMsgID,
+		User:        c.User,
+		Content:     incoming.Content,
+		Timestamp:   time.Now(),
+		RoomID:      env.Room,
+		Target:      incoming.Target,
+	}
+
+	// o reenvio de um client_msg_id já gravado não gera outra mensagem: só
+	// publica, se a primeira tentativa não chegou a publicar, e confirma
+	if clientMsgID != "" {
+		sent, err := c.Hub.ChatStore.ClaimClientMsgID(ctx, env.Room, c.User, clientMsgID, id)
 		if err != nil {
-			break
+			c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+			return
+		}
+		if sent.MessageID != id {
+			stored, err := c.Hub.ChatStore.GetMessage(ctx, env.Room, sent.MessageID)
+			switch {
+			case err == nil:
+				c.confirmMessage(ctx, env, stored, sent.Published)
+				return
+			case errors.Is(err, redis.ErrMessageNotFound):
+				// a primeira tentativa falhou antes de gravar; segue com o ID novo
+				if err := c.Hub.ChatStore.SetClientMsgID(ctx, env.Room, c.User, clientMsgID, redis.SentMessage{MessageID: id}); err != nil {
+					c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+					return
+				}
+			default:
+				c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+				return
+			}
+		}
+	}
+
+	// grava antes de publicar: quem recebe ao vivo já encontra a mensagem no histórico
+	if err := c.Hub.ChatStore.SaveMessage(ctx, env.Room, msg, c.Hub.retentionFor(ctx, env.Room)); err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+	c.confirmMessage(ctx, env, msg, false)
+}
+
+// confirmMessage publica a mensagem já gravada, se ainda não foi publicada, e
+// envia o ack; com falha na publicação o cliente pode reenviar com o mesmo
+// client_msg_id sem duplicar a mensagem
+func (c *Client) confirmMessage(ctx context.Context, env dto.Envelope, msg dto.Message, published bool) {
+	if !published {
+		if err := c.Hub.publisher.PublishMessage(ctx, "chat:"+env.Room, msg); err != nil {
+			c.sendDeliveryError(env.ID, env.Room, msg.ClientMsgID)
+			return
+		}
+		if msg.ClientMsgID != "" {
+			_ = c.Hub.ChatStore.SetClientMsgID(ctx, env.Room, c.User, msg.ClientMsgID, redis.SentMessage{MessageID: msg.ID, Published: true})
 		}
 	}
+
+	c.sendEvent(dto.EventAck, env.ID, env.Room, dto.AckEvent{
+		ClientMsgID: msg.ClientMsgID,
+		MessageID:   msg.ID,
+		Timestamp:   msg.Timestamp,
+	})
+
+	// quem envia já leu tudo até a própria mensagem
+	if advanced, err := c.Hub.ChatStore.AdvanceReadCursor(ctx, env.Room, c.User, msg.ID); err == nil && advanced {
+		c.publishReceipt(ctx, env.Room, msg.ID)
+	}
 }
 
-func RefreshHandler(w http.ResponseWriter, r *http.Request) {
-	refreshToken := r.Header.Get("Authorization")
-	if len(refreshToken) > 7 && refreshToken[:7] == "Bearer " {
-		refreshToken = refreshToken[7:]
+// handleResume reenvia as mensagens posteriores ao ID informado pelo cliente
+func (c *Client) handleResume(env dto.Envelope) {
+	var req dto.ResumeRequest
+	if err := json.Unmarshal(env.Payload, &req); err != nil || req.Since == "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid resume payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermReadHistory) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot read the history of "+env.Room)
+		return
 	}
 
-	user, err := auth.ValidateRefreshToken(refreshToken)
+	c.sendHistory(ctx, env.ID, env.Room, req.Since)
+}
+
+// handleHistory envia uma página do histórico anterior a before, para o
+// cliente rolar a conversa sem reconectar
+func (c *Client) handleHistory(env dto.Envelope) {
+	var req dto.HistoryRequest
+	if len(env.Payload) > 0 {
+		if err := json.Unmarshal(env.Payload, &req); err != nil || req.Limit < 0 {
+			c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid history payload")
+			return
+		}
+	}
+	limit := historyLimit
+	if req.Limit > 0 {
+		limit = min(req.Limit, maxHistoryPage)
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermReadHistory) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot read the history of "+env.Room)
+		return
+	}
+
+	history, more, err := c.Hub.ChatStore.GetMessagesBefore(ctx, env.Room, req.Before, limit, c.User)
+	if errors.Is(err, redis.ErrInvalidMessageID) {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid before message id")
+		return
+	}
 	if err != nil {
-		http.Error(w, "invalid refresh token", http.StatusUnauthorized)
+		c.sendError(env.ID, env.Room, ErrCodeUnavailable, "could not load history")
 		return
 	}
+	c.sendEvent(dto.EventHistory, env.ID, env.Room, dto.HistoryEvent{Messages: history, HasMore: more})
+}
+
+// handleJoin inscreve a conexão em mais uma sala, autorizada pelas salas do
+// token e pelos papéis da sala; since opcional no payload evita reenviar o
+// histórico que o cliente já tem
+func (c *Client) handleJoin(env dto.Envelope) {
+	var req dto.JoinRequest
+	if len(env.Payload) > 0 {
+		if err := json.Unmarshal(env.Payload, &req); err != nil {
+			c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid join payload")
+			return
+		}
+	}
 
-	// Salas permitidas (geralmente buscaria no DB)
-	rooms := []string{"default", "vip"}
-	newAccessToken, _ := auth.GenerateAccessToken(user, rooms)
+	ctx := context.Background()
+	role, err := c.Hub.rooms.RoleOf(ctx, env.Room, c.User, c.Claims.Rooms)
+	if err != nil {
+		c.sendError(env.ID, env.Room, ErrCodeUnavailable, "could not load room")
+		return
+	}
+	if role == room.RoleNone {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "no access to room "+env.Room)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"access_token":"` + newAccessToken + `"}`))
+	if c.addRoom(env.Room) {
+		c.Hub.Join <- membership{client: c, roomID: env.Room}
+		if role.Can(room.PermReadHistory) {
+			c.sendHistory(ctx, env.ID, env.Room, req.Since)
+		}
+	}
+	c.sendEvent(dto.EventSystem, env.ID, env.Room, dto.SystemEvent{
+		Event:   "joined",
+		Message: "joined " + env.Room,
+		Role:    string(role),
+	})
+	c.sendPresence(ctx, env.ID, env.Room)
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	// usuário + senha (aqui só um exemplo simples)
-	user := r.FormValue("user")
-	password := r.FormValue("password")
+// handleLeave tira a conexão de uma sala; a conexão continua aberta mesmo
+// sem nenhuma sala, podendo entrar em outra com join
+func (c *Client) handleLeave(env dto.Envelope) {
+	if !c.inRoom(env.Room) {
+		c.sendError(env.ID, env.Room, ErrCodeNotJoined, "not joined to room "+env.Room)
+		return
+	}
 
-	if password != "1234" { // exemplo fixo, no real você verifica DB
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	c.removeRoom(env.Room)
+	c.Hub.Leave <- membership{client: c, roomID: env.Room}
+	c.sendEvent(dto.EventSystem, env.ID, env.Room, dto.SystemEvent{
+		Event:   "left",
+		Message: "left " + env.Room,
+	})
+}
+
+// sendHistory envia o histórico da sala: as últimas mensagens quando since é
+// vazio, ou exatamente as posteriores a since, com Gap quando parte delas já
+// foi descartada e o cliente precisa recarregar
+func (c *Client) sendHistory(ctx context.Context, id, roomID, since string) {
+	if since == "" {
+		if history, err := c.Hub.ChatStore.GetMessages(ctx, roomID, historyLimit, c.User); err == nil {
+			c.sendEvent(dto.EventHistory, id, roomID, dto.HistoryEvent{Messages: history})
+		}
 		return
 	}
 
-	// salas que o usuário pode acessar
-	rooms := []string{"default", "vip"}
+	history, err := c.Hub.ChatStore.GetMessagesSince(ctx, roomID, since, c.User)
+	switch {
+	case errors.Is(err, redis.ErrInvalidMessageID):
+		c.sendError(id, roomID, ErrCodeBadRequest, "invalid since message id")
+	case errors.Is(err, redis.ErrHistoryGap):
+		c.sendEvent(dto.EventHistory, id, roomID, dto.HistoryEvent{Messages: history, Gap: true})
+	case err == nil:
+		c.sendEvent(dto.EventHistory, id, roomID, dto.HistoryEvent{Messages: history})
+	}
+}
+
+// currentRole confere se a conexão está na sala do frame e consulta o papel a
+// cada frame, para que mudanças valham na hora; retorna false quando o frame
+// deve ser descartado
+func (c *Client) currentRole(ctx context.Context, env dto.Envelope) (room.Role, bool) {
+	if !c.inRoom(env.Room) {
+		c.sendError(env.ID, env.Room, ErrCodeNotJoined, "not joined to room "+env.Room)
+		return room.RoleNone, false
+	}
+
+	role, err := c.Hub.rooms.RoleOf(ctx, env.Room, c.User, c.Claims.Rooms)
+	if err != nil {
+		// falha do Redis não é falta de permissão: o cliente pode tentar de novo
+		c.Hub.logger.InfoF("Erro ao consultar o papel de %s na sala %s: %v", c.User, env.Room, err)
+		c.sendError(env.ID, env.Room, ErrCodeUnavailable, "could not check room permissions")
+		return room.RoleNone, false
+	}
+	if role == room.RoleNone {
+		// perdeu o acesso à sala depois de entrar
+		c.removeRoom(env.Room)
+		c.Hub.Leave <- membership{client: c, roomID: env.Room}
+		c.evicted(env.Room, "no access to room")
+		return role, false
+	}
+	return role, true
+}
+
+// evicted avisa que a conexão saiu da sala por perda de acesso e a fecha
+// quando não resta nenhuma sala
+func (c *Client) evicted(roomID, reason string) {
+	c.sendEvent(dto.EventSystem, "", roomID, dto.SystemEvent{Event: "removed", Message: reason})
+	if len(c.Rooms()) == 0 {
+		c.closeWith(CloseForbidden, reason)
+	}
+}
+
+func (c *Client) inRoom(roomID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rooms[roomID]
+}
+
+// addRoom retorna false quando a conexão já estava na sala
+func (c *Client) addRoom(roomID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rooms[roomID] {
+		return false
+	}
+	c.rooms[roomID] = true
+	return true
+}
+
+func (c *Client) removeRoom(roomID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.rooms, roomID)
+}
+
+// Rooms lista as salas em que a conexão está
+func (c *Client) Rooms() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for roomID := range c.rooms {
+		rooms = append(rooms, roomID)
+	}
+	return rooms
+}
+
+// queue enfileira o frame sem bloquear; retorna false quando o buffer está
+// cheio. Depois do closeSend os frames são descartados.
+func (c *Client) queue(frame []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return true
+	}
+	select {
+	case c.Send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend fecha o canal de envio uma única vez
+func (c *Client) closeSend() {
+	c.closeSendWith(nil)
+}
 
-	accessToken, _ := auth.GenerateAccessToken(user, rooms)
-	refreshToken, _ := auth.GenerateRefreshToken(user)
+// closeSendWith fecha o canal de envio guardando o frame de fechamento que o
+// writePump escreve depois de esvaziar a fila
+func (c *Client) closeSendWith(frame []byte) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.closeFrame = frame
+		close(c.Send)
+	}
+}
+
+// writePump envia os frames da fila e o ping periódico; cada escrita tem o
+// prazo WriteWait para não travar em conexões meio abertas
+func (c *Client) writePump() {
+	cfg := c.Hub.conn
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.Send:
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if !ok {
+				// o canal foi fechado pelo Hub ou por closeWith; os frames
+				// enfileirados antes já foram escritos
+				_ = c.Conn.WriteMessage(websocket.CloseMessage, c.closeFrame)
+				return
+			}
+			if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"access_token":"` + accessToken + `","refresh_token":"` + refreshToken + `"}`))
+// closeWith encerra a conexão pelo writePump, que envia o frame de fechamento
+// com o código e o motivo depois dos eventos já enfileirados (como o removed);
+// o readPump percebe o fechamento e desregistra o cliente do Hub
+func (c *Client) closeWith(code int, reason string) {
+	c.closeSendWith(websocket.FormatCloseMessage(code, reason))
 }
@@ -0,0 +1,29 @@
+package websocket
+
+import "time"
+
+// Config define os limites das conexões: o servidor envia ping a cada
+// PingInterval e fecha a conexão que passar PongWait sem responder
+type Config struct {
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+}
+
+func (c Config) withDefaults() Config {
+	if c.PongWait <= 0 {
+		c.PongWait = 60 * time.Second
+	}
+	// o ping precisa chegar antes do prazo do pong expirar
+	if c.PingInterval <= 0 || c.PingInterval >= c.PongWait {
+		c.PingInterval = c.PongWait * 9 / 10
+	}
+	if c.WriteWait <= 0 {
+		c.WriteWait = 10 * time.Second
+	}
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = 32 * 1024
+	}
+	return c
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+)
+
+var (
+	errNotAuthor      = errors.New("only the author can edit this message")
+	errCannotDelete   = errors.New("you cannot delete this message")
+	errAlreadyDeleted = errors.New("message was deleted")
+)
+
+// handleEdit troca o conteúdo de uma mensagem; só o autor pode editar
+func (c *Client) handleEdit(env dto.Envelope) {
+	var req dto.MessageChange
+	if err := json.Unmarshal(env.Payload, &req); err != nil || req.MessageID == "" || req.Content == "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid edit payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermSend) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot send messages in "+env.Room)
+		return
+	}
+
+	now := time.Now()
+	c.changeMessage(ctx, env, req.MessageID, func(msg *dto.Message) error {
+		if msg.Deleted {
+			return errAlreadyDeleted
+		}
+		if msg.User != c.User {
+			return errNotAuthor
+		}
+		msg.Content = req.Content
+		msg.EditedAt = &now
+		return nil
+	})
+}
+
+// handleDelete troca a mensagem por uma lápide; o autor sempre pode apagar a
+// própria mensagem e quem tem delete_others apaga as dos outros
+func (c *Client) handleDelete(env dto.Envelope) {
+	var req dto.MessageChange
+	if err := json.Unmarshal(env.Payload, &req); err != nil || req.MessageID == "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid delete payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	c.changeMessage(ctx, env, req.MessageID, func(msg *dto.Message) error {
+		if msg.Deleted {
+			return errAlreadyDeleted
+		}
+		if msg.User != c.User && !role.Can(room.PermDeleteOthers) {
+			return errCannotDelete
+		}
+		msg.Content = ""
+		msg.Deleted = true
+		msg.DeletedAt = &now
+		msg.DeletedBy = c.User
+		return nil
+	})
+}
+
+// changeMessage grava a alteração no histórico, confirma com ack e avisa as
+// conexões da sala em todas as instâncias
+func (c *Client) changeMessage(ctx context.Context, env dto.Envelope, id string, update func(*dto.Message) error) {
+	msg, err := c.Hub.ChatStore.UpdateMessage(ctx, env.Room, id, update)
+	switch {
+	case errors.Is(err, redis.ErrMessageNotFound):
+		c.sendError(env.ID, env.Room, ErrCodeNotFound, "message not found in history")
+		return
+	case errors.Is(err, errNotAuthor), errors.Is(err, errCannotDelete):
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, err.Error())
+		return
+	case errors.Is(err, errAlreadyDeleted):
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, err.Error())
+		return
+	case err != nil:
+		c.sendError(env.ID, env.Room, ErrCodeDeliveryFailed, "message could not be changed")
+		return
+	}
+
+	_ = c.Hub.publisher.PublishEvent(ctx, "events:"+env.Room, dto.RoomEvent{
+		Type:      env.Type,
+		RoomID:    env.Room,
+		User:      c.User,
+		MessageID: msg.ID,
+		Message:   &msg,
+	})
+	c.sendEvent(dto.EventAck, env.ID, env.Room, dto.AckEvent{MessageID: msg.ID, Timestamp: time.Now()})
+}
+
+// deliverChange repassa a mensagem editada ou apagada; a de uma mensagem
+// privada só vai para o autor e o destinatário
+func (h *Hub) deliverChange(event dto.RoomEvent) {
+	if event.Message == nil {
+		return
+	}
+	msg := event.Message
+	frame := encodeEnvelope(event.Type, msg.ID, event.RoomID, msg)
+
+	if msg.Target != "" {
+		for client := range h.clients {
+			if msg.VisibleTo(client.User) {
+				h.deliver(client, frame)
+			}
+		}
+		return
+	}
+	for client := range h.Rooms[event.RoomID] {
+		h.deliver(client, frame)
+	}
+}
@@ -2,46 +2,110 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
+	"time"
 
+	"github.com/brunobotter/chat-websocket/auth"
 	"github.com/brunobotter/chat-websocket/dto"
 	"github.com/brunobotter/chat-websocket/logger"
+	"github.com/brunobotter/chat-websocket/presence"
 	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+	"github.com/gorilla/websocket"
 )
 
+// historyLimit é o número de mensagens enviadas do histórico ao conectar ou entrar na sala
+const historyLimit = 50
+
+// maxHistoryPage é o máximo de mensagens por página pedida com o frame history
+const maxHistoryPage = 100
+
+// intervalo de verificação de tokens expirados nas conexões abertas
+const tokenCheckInterval = 15 * time.Second
+
+// membership é a entrada ou saída de uma conexão em uma sala
+type membership struct {
+	client *Client
+	roomID string
+}
+
+// Hub mantém as conexões registradas e as salas de cada uma; os mapas só são
+// alterados pela goroutine do Run
 type Hub struct {
 	Rooms      map[string]map[*Client]bool
 	Broadcast  chan dto.Message
 	Register   chan *Client
 	Unregister chan *Client
-	logger     logger.Logger
-	ChatStore  redis.MessageStore
+	Join       chan membership
+	Leave      chan membership
+	Revoke     chan auth.Revocation
+	Kick       chan dto.Kick
+	Events     chan dto.RoomEvent
+	clients    map[*Client]bool
+	typists    map[string]map[string]time.Time
+
+	presenceChanges chan presenceChange
+	logger          logger.Logger
+	ChatStore       redis.MessageStore
+	publisher       redis.Publisher
+	rooms           room.Store
+	presence        presence.Store
+	instance        string
+	origins         *OriginPolicy
+	upgrader        websocket.Upgrader
+	conn            Config
+	retention       redis.Retention
 }
 
-func NewHub(logger logger.Logger, chatStore redis.MessageStore) *Hub {
+func NewHub(logger logger.Logger, chatStore redis.MessageStore, publisher redis.Publisher, rooms room.Store, presence presence.Store, origins *OriginPolicy, conn Config, retention redis.Retention) *Hub {
 	return &Hub{
 		Rooms:      make(map[string]map[*Client]bool),
 		Broadcast:  make(chan dto.Message),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		Join:       make(chan membership),
+		Leave:      make(chan membership),
+		Revoke:     make(chan auth.Revocation),
+		Kick:       make(chan dto.Kick),
+		Events:     make(chan dto.RoomEvent),
+		clients:    make(map[*Client]bool),
+		typists:    make(map[string]map[string]time.Time),
 		logger:     logger,
 		ChatStore:  chatStore,
+		publisher:  publisher,
+		rooms:      rooms,
+		presence:   presence,
+		instance:   newInstanceID(),
+		origins:    origins,
+		upgrader:   newUpgrader(origins),
+		conn:       conn.withDefaults(),
+		retention:  retention,
+
+		presenceChanges: make(chan presenceChange, 1024),
 	}
 }
 
+// RejectedOrigins retorna quantas conexões a política de origens recusou
+// desde que a instância subiu
+func (h *Hub) RejectedOrigins() int64 {
+	return h.origins.Rejected()
+}
+
 func (h *Hub) Run() {
 	ctx := context.Background()
+	tokenCheck := time.NewTicker(tokenCheckInterval)
+	defer tokenCheck.Stop()
+	typingSweep := time.NewTicker(typingSweepInterval)
+	defer typingSweep.Stop()
+	go h.runPresence(ctx)
+
 	for {
 		select {
 		//registro clientes
 		case client := <-h.Register:
-			if client.RoomID == "" {
-				client.RoomID = "default"
+			h.clients[client] = true
+			for _, roomID := range client.Rooms() {
+				h.join(client, roomID)
 			}
-			if _, ok := h.Rooms[client.RoomID]; !ok {
-				h.Rooms[client.RoomID] = make(map[*Client]bool)
-			}
-			h.Rooms[client.RoomID][client] = true
 			if h.ChatStore != nil {
 				go func(c *Client) {
 
@@ -49,9 +113,8 @@ func (h *Hub) Run() {
 					if err != nil {
 						return
 					}
-					for _, msg := range unread {
-						payload, _ := json.Marshal(msg)
-						c.Send <- payload
+					if len(unread) > 0 {
+						c.sendEvent(dto.EventHistory, "", "", dto.HistoryEvent{Messages: unread, Unread: true})
 					}
 					// Limpa mensagens não lidas depois de enviar
 					_ = h.ChatStore.ClearUnread(ctx, c.User)
@@ -59,27 +122,22 @@ func (h *Hub) Run() {
 			}
 			//deregistro de clientes
 		case client := <-h.Unregister:
-			if clients, ok := h.Rooms[client.RoomID]; ok {
-				delete(clients, client)
-				close(client.Send)
-				if len(clients) == 0 {
-					delete(h.Rooms, client.RoomID)
-				}
+			h.drop(client)
+			//entrada e saída de salas de uma conexão já registrada
+		case m := <-h.Join:
+			if h.clients[m.client] {
+				h.join(m.client, m.roomID)
 			}
+		case m := <-h.Leave:
+			h.leave(m.client, m.roomID)
 			//recebimento de mensagens
 		case msg := <-h.Broadcast:
-			//mensagens privadas
+			frame := messageFrame(msg)
+			//mensagens privadas vão para todas as conexões do destinatário
 			if msg.Target != "" {
-				for _, clients := range h.Rooms {
-					for client := range clients {
-						if client.User == msg.Target {
-							select {
-							case client.Send <- []byte(msg.Content):
-							default:
-								close(client.Send)
-								delete(clients, client)
-							}
-						}
+				for client := range h.clients {
+					if client.User == msg.Target {
+						h.deliver(client, frame)
 					}
 				}
 				// Salva mensagem como não lida no Redis
@@ -88,21 +146,116 @@ func (h *Hub) Run() {
 				}
 				continue
 			}
-			//broadcast por sala
-			if clients, ok := h.Rooms[msg.RoomID]; ok {
-				for client := range clients {
-					select {
-					case client.Send <- []byte(msg.Content):
-					default:
-						delete(clients, client)
-						close(client.Send)
-					}
-				}
+			//broadcast por sala; a mensagem encerra a digitação do autor
+			h.stopTyping(msg.RoomID, msg.User)
+			for client := range h.Rooms[msg.RoomID] {
+				h.deliver(client, frame)
 			}
-			// Salva a mensagem da sala no Redis
-			if h.ChatStore != nil {
-				_ = h.ChatStore.SaveMessage(ctx, msg.RoomID, msg, 50) // exemplo com limite de 50
+			//eventos efêmeros das salas (de qualquer instância)
+		case event := <-h.Events:
+			switch event.Type {
+			case dto.EventPresence:
+				h.deliverPresence(event)
+			case dto.EventRead:
+				h.deliverReceipt(event)
+			case dto.EventEdit, dto.EventDelete:
+				h.deliverChange(event)
+			case dto.EventReact, dto.EventUnreact:
+				h.deliverReaction(event)
+			case dto.EventThreadReply:
+				h.deliverThreadReply(event)
+			default:
+				h.typing(event)
 			}
+		case now := <-typingSweep.C:
+			h.expireTyping(now)
+			//revogação de tokens (de qualquer instância)
+		case revocation := <-h.Revoke:
+			h.disconnect(func(c *Client) bool { return revocation.Matches(c.Claims) }, CloseUnauthorized, "token revoked")
+			//expulsão de usuários da sala (de qualquer instância)
+		case kick := <-h.Kick:
+			h.kick(kick)
+			//expiração dos tokens das conexões abertas
+		case now := <-tokenCheck.C:
+			h.disconnect(func(c *Client) bool {
+				return c.Claims.ExpiresAt != nil && now.After(c.Claims.ExpiresAt.Time)
+			}, CloseUnauthorized, "token expired")
+		}
+	}
+}
+
+func (h *Hub) join(client *Client, roomID string) {
+	if !h.hasUser(roomID, client.User) {
+		h.presenceChanges <- presenceChange{roomID: roomID, user: client.User, online: true}
+	}
+	if _, ok := h.Rooms[roomID]; !ok {
+		h.Rooms[roomID] = make(map[*Client]bool)
+	}
+	h.Rooms[roomID][client] = true
+}
+
+func (h *Hub) leave(client *Client, roomID string) {
+	clients, ok := h.Rooms[roomID]
+	if !ok || !clients[client] {
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.Rooms, roomID)
+	}
+	if !h.hasUser(roomID, client.User) {
+		h.presenceChanges <- presenceChange{roomID: roomID, user: client.User, online: false}
+	}
+}
+
+// drop remove a conexão de todas as salas e fecha o canal de envio; o
+// writePump então envia o frame de fechamento e encerra a conexão
+func (h *Hub) drop(client *Client) {
+	if !h.clients[client] {
+		return
+	}
+	delete(h.clients, client)
+	for _, roomID := range client.Rooms() {
+		h.leave(client, roomID)
+	}
+	client.closeSend()
+}
+
+// deliver entrega o frame sem bloquear o Hub; conexões com o buffer cheio são descartadas
+func (h *Hub) deliver(client *Client, frame []byte) {
+	if !client.queue(frame) {
+		h.drop(client)
+	}
+}
+
+// kick tira o usuário da sala em todas as conexões dele; a conexão que ficar
+// sem nenhuma sala é fechada
+func (h *Hub) kick(kick dto.Kick) {
+	for client := range h.Rooms[kick.RoomID] {
+		if client.User != kick.User {
+			continue
+		}
+		client.removeRoom(kick.RoomID)
+		h.leave(client, kick.RoomID)
+		h.deliver(client, encodeEnvelope(dto.EventSystem, "", kick.RoomID, dto.SystemEvent{
+			Event:   "removed",
+			Message: "removed from room",
+		}))
+		if len(client.Rooms()) == 0 {
+			h.logger.InfoF("Encerrando conexão de %s: %s", client.User, "removed from room")
+			client.closeWith(CloseForbidden, "removed from room")
+		}
+	}
+}
+
+// disconnect fecha as conexões selecionadas; a remoção das salas acontece
+// quando o readPump do cliente envia o Unregister
+func (h *Hub) disconnect(match func(*Client) bool, code int, reason string) {
+	for client := range h.clients {
+		if client.Claims == nil || !match(client) {
+			continue
 		}
+		h.logger.InfoF("Encerrando conexão de %s: %s", client.User, reason)
+		client.closeWith(code, reason)
 	}
 }
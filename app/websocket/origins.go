@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/brunobotter/chat-websocket/logger"
+)
+
+// OriginPolicy decide quais origens podem abrir WebSockets, evitando
+// cross-site WebSocket hijacking. Entradas aceitas:
+//
+//	https://chat.example.com     origem exata
+//	https://*.example.com        qualquer subdomínio (não inclui o domínio raiz)
+//	re:^https://.*\.example\.org$ expressão regular sobre a origem completa
+//
+// Com a lista vazia vale a regra padrão do gorilla: só a mesma origem do host.
+// Requisições sem header Origin (clientes que não são navegadores) são aceitas.
+type OriginPolicy struct {
+	exact     map[string]bool
+	wildcards []string
+	patterns  []*regexp.Regexp
+	rejected  atomic.Int64
+	logger    logger.Logger
+}
+
+func NewOriginPolicy(entries []string, logger logger.Logger) (*OriginPolicy, error) {
+	p := &OriginPolicy{
+		exact:  make(map[string]bool),
+		logger: logger,
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case strings.HasPrefix(entry, "re:"):
+			re, err := regexp.Compile(strings.TrimPrefix(entry, "re:"))
+			if err != nil {
+				return nil, fmt.Errorf("websocket: invalid origin pattern %q: %w", entry, err)
+			}
+			p.patterns = append(p.patterns, re)
+		case strings.Contains(entry, "://*."):
+			// guarda "https://" + ".example.com" para comparar esquema e sufixo
+			p.wildcards = append(p.wildcards, strings.ToLower(strings.Replace(entry, "://*.", "://.", 1)))
+		default:
+			p.exact[strings.ToLower(strings.TrimSuffix(entry, "/"))] = true
+		}
+	}
+	return p, nil
+}
+
+func (p *OriginPolicy) empty() bool {
+	return len(p.exact) == 0 && len(p.wildcards) == 0 && len(p.patterns) == 0
+}
+
+// Check é usado como CheckOrigin do upgrader
+func (p *OriginPolicy) Check(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if p.allowed(r, origin) {
+		return true
+	}
+
+	total := p.rejected.Add(1)
+	p.logger.WithFields(map[string]any{
+		"origin":         origin,
+		"remote_addr":    r.RemoteAddr,
+		"rejected_total": total,
+	}).InfoF("Origem de WebSocket recusada: %s", origin)
+	return false
+}
+
+// Rejected retorna quantas tentativas de conexão foram recusadas pela origem
+func (p *OriginPolicy) Rejected() int64 {
+	return p.rejected.Load()
+}
+
+func (p *OriginPolicy) allowed(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if p.empty() {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	if p.exact[normalized] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		scheme, suffix, _ := strings.Cut(w, "://")
+		if u.Scheme == scheme && strings.HasSuffix(strings.ToLower(u.Host), suffix) {
+			return true
+		}
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
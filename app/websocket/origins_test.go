@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brunobotter/chat-websocket/logger"
+)
+
+func TestOriginPolicyCheck(t *testing.T) {
+	entries := []string{
+		"https://chat.example.com/",
+		"https://*.example.net",
+		`re:^https://[a-z]+\.example\.org$`,
+	}
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{"sem origin", "", true},
+		{"origem exata", "https://chat.example.com", true},
+		{"origem exata com maiúsculas", "https://CHAT.example.com", true},
+		{"origem exata com outro esquema", "http://chat.example.com", false},
+		{"outro host do mesmo domínio", "https://evil.example.com", false},
+		{"sufixo sem ponto", "https://chat.example.com.evil.io", false},
+		{"subdomínio do curinga", "https://app.example.net", true},
+		{"subdomínio profundo do curinga", "https://a.b.example.net", true},
+		{"domínio raiz do curinga", "https://example.net", false},
+		{"curinga com outro esquema", "http://app.example.net", false},
+		{"domínio parecido com o curinga", "https://app.badexample.net", false},
+		{"expressão regular", "https://docs.example.org", true},
+		{"expressão regular ancorada", "https://docs.example.org.evil.io", false},
+		{"origem inválida", "null", false},
+	}
+
+	policy, err := NewOriginPolicy(entries, logger.NewLoggerZap("test"))
+	if err != nil {
+		t.Fatalf("NewOriginPolicy: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8000/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := policy.Check(r); got != tt.want {
+				t.Errorf("Check(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOriginPolicySameHostByDefault(t *testing.T) {
+	policy, err := NewOriginPolicy(nil, logger.NewLoggerZap("test"))
+	if err != nil {
+		t.Fatalf("NewOriginPolicy: %v", err)
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:8000", true},
+		{"https://localhost:8000", true},
+		{"http://localhost:9000", false},
+		{"http://evil.io", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8000/ws", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := policy.Check(r); got != tt.want {
+			t.Errorf("Check(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+	if got := policy.Rejected(); got != 2 {
+		t.Errorf("Rejected() = %d, want 2", got)
+	}
+}
+
+func TestNewOriginPolicyInvalidPattern(t *testing.T) {
+	if _, err := NewOriginPolicy([]string{"re:("}, logger.NewLoggerZap("test")); err == nil {
+		t.Error("NewOriginPolicy aceitou uma expressão regular inválida")
+	}
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/presence"
+)
+
+// intervalo de renovação da presença desta instância no Redis, bem abaixo do presence.TTL
+const presenceHeartbeat = 10 * time.Second
+
+// presenceChange é a primeira conexão de um usuário em uma sala nesta
+// instância (online) ou o fim da última (offline)
+type presenceChange struct {
+	roomID string
+	user   string
+	online bool
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hasUser indica se o usuário tem alguma conexão na sala nesta instância
+func (h *Hub) hasUser(roomID, user string) bool {
+	for client := range h.Rooms[roomID] {
+		if client.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// runPresence grava as mudanças de presença no Redis fora da goroutine do
+// Run, na ordem em que aconteceram, e renova periodicamente as entradas
+// desta instância
+func (h *Hub) runPresence(ctx context.Context) {
+	local := make(map[string][]string)
+	heartbeat := time.NewTicker(presenceHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case change := <-h.presenceChanges:
+			if change.online {
+				local[change.roomID] = append(local[change.roomID], change.user)
+				h.presenceOnline(ctx, change.roomID, change.user)
+			} else {
+				local[change.roomID] = slices.DeleteFunc(local[change.roomID], func(u string) bool { return u == change.user })
+				if len(local[change.roomID]) == 0 {
+					delete(local, change.roomID)
+				}
+				h.presenceOffline(ctx, change.roomID, change.user)
+			}
+		case <-heartbeat.C:
+			if err := h.presence.Heartbeat(ctx, h.instance, local); err != nil {
+				h.logger.InfoF("Falha ao renovar a presença da instância %s: %v", h.instance, err)
+			}
+		}
+	}
+}
+
+// presenceOnline só anuncia a entrada quando o usuário não estava na sala em outra instância
+func (h *Hub) presenceOnline(ctx context.Context, roomID, user string) {
+	already, _ := h.presence.IsOnline(ctx, roomID, user)
+	if err := h.presence.Join(ctx, h.instance, roomID, user); err != nil {
+		return
+	}
+	if !already {
+		h.publishPresence(ctx, roomID, user, presence.Online)
+	}
+}
+
+// presenceOffline só anuncia a saída quando o usuário não continua na sala em outra instância
+func (h *Hub) presenceOffline(ctx context.Context, roomID, user string) {
+	if err := h.presence.Leave(ctx, h.instance, roomID, user); err != nil {
+		return
+	}
+	if online, err := h.presence.IsOnline(ctx, roomID, user); err == nil && !online {
+		h.publishPresence(ctx, roomID, user, presence.Offline)
+	}
+}
+
+func (h *Hub) publishPresence(ctx context.Context, roomID, user, status string) {
+	_ = h.publisher.PublishEvent(ctx, "events:"+roomID, dto.RoomEvent{
+		Type:   dto.EventPresence,
+		RoomID: roomID,
+		User:   user,
+		Status: status,
+	})
+}
+
+// deliverPresence repassa uma entrada ou saída às conexões da sala
+func (h *Hub) deliverPresence(event dto.RoomEvent) {
+	frame := encodeEnvelope(dto.EventPresence, "", event.RoomID, dto.PresenceEvent{User: event.User, Status: event.Status})
+	for client := range h.Rooms[event.RoomID] {
+		h.deliver(client, frame)
+	}
+}
+
+// sendPresence envia ao cliente quem está na sala em todo o cluster
+func (c *Client) sendPresence(ctx context.Context, id, roomID string) {
+	users, err := c.Hub.presence.Online(ctx, roomID)
+	if err != nil {
+		return
+	}
+	// a entrada desta conexão pode ainda não ter sido gravada
+	if !slices.Contains(users, c.User) {
+		users = append(users, c.User)
+		slices.Sort(users)
+	}
+	c.sendEvent(dto.EventPresence, id, roomID, dto.PresenceEvent{Users: users})
+}
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/brunobotter/chat-websocket/dto"
+)
+
+// Códigos enviados no payload dos eventos de erro
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeUnsupportedType    = "unsupported_type"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeDeliveryFailed     = "delivery_failed"
+	ErrCodeNotJoined          = "not_joined"
+	ErrCodeUnavailable        = "unavailable"
+	ErrCodeNotFound           = "not_found"
+)
+
+// encodeEnvelope monta o frame no formato do protocolo
+func encodeEnvelope(eventType dto.EventType, id, roomID string, payload any) []byte {
+	raw, _ := json.Marshal(payload)
+	frame, _ := json.Marshal(dto.Envelope{
+		Version: dto.ProtocolVersion,
+		Type:    eventType,
+		ID:      id,
+		Room:    roomID,
+		Payload: raw,
+	})
+	return frame
+}
+
+// decodeEnvelope lê um frame do cliente; a versão omitida vale como a atual
+func decodeEnvelope(frame []byte) (dto.Envelope, error) {
+	var env dto.Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return env, err
+	}
+	if env.Version == 0 {
+		env.Version = dto.ProtocolVersion
+	}
+	return env, nil
+}
+
+// messageFrame é o frame de uma mensagem entregue ao vivo
+func messageFrame(msg dto.Message) []byte {
+	return encodeEnvelope(dto.EventMessage, msg.ID, msg.RoomID, msg)
+}
+
+// sendEvent enfileira um evento para o cliente; com o buffer cheio a conexão é
+// encerrada e o readPump a desregistra do Hub
+func (c *Client) sendEvent(eventType dto.EventType, id, roomID string, payload any) {
+	if !c.queue(encodeEnvelope(eventType, id, roomID, payload)) {
+		c.Conn.Close()
+	}
+}
+
+func (c *Client) sendError(id, roomID, code, message string) {
+	c.sendEvent(dto.EventError, id, roomID, dto.ErrorEvent{Code: code, Message: message})
+}
+
+// sendDeliveryError avisa que a mensagem não foi gravada ou publicada; o
+// cliente pode reenviar com o mesmo client_msg_id
+func (c *Client) sendDeliveryError(id, roomID, clientMsgID string) {
+	c.sendEvent(dto.EventError, id, roomID, dto.ErrorEvent{
+		Code:        ErrCodeDeliveryFailed,
+		Message:     "message could not be delivered",
+		ClientMsgID: clientMsgID,
+	})
+}
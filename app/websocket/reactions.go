@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+)
+
+// tamanho máximo de um emoji ou shortcode (ex.: ":thumbsup:")
+const maxEmojiLength = 32
+
+// handleReaction adiciona (react) ou remove (unreact) a reação do usuário a
+// uma mensagem da sala e publica a mudança para todas as instâncias
+func (c *Client) handleReaction(env dto.Envelope) {
+	var req dto.ReactionChange
+	if err := json.Unmarshal(env.Payload, &req); err != nil || req.MessageID == "" || !validEmoji(req.Emoji) {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid reaction payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermSend) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot react in "+env.Room)
+		return
+	}
+
+	msg, err := c.Hub.ChatStore.GetMessage(ctx, env.Room, req.MessageID)
+	if errors.Is(err, redis.ErrMessageNotFound) {
+		c.sendError(env.ID, env.Room, ErrCodeNotFound, "message not found in history")
+		return
+	}
+	if err != nil {
+		c.sendError(env.ID, env.Room, ErrCodeUnavailable, "could not load message")
+		return
+	}
+	// reações em mensagens privadas seriam entregues à sala inteira
+	if msg.Deleted || msg.Target != "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "cannot react to this message")
+		return
+	}
+
+	var changed bool
+	if env.Type == dto.EventReact {
+		changed, err = c.Hub.ChatStore.React(ctx, env.Room, msg.ID, c.User, req.Emoji, c.Hub.retentionFor(ctx, env.Room))
+	} else {
+		changed, err = c.Hub.ChatStore.Unreact(ctx, env.Room, msg.ID, c.User, req.Emoji)
+	}
+	if err != nil {
+		c.sendError(env.ID, env.Room, ErrCodeDeliveryFailed, "reaction could not be saved")
+		return
+	}
+	if changed {
+		_ = c.Hub.publisher.PublishEvent(ctx, "events:"+env.Room, dto.RoomEvent{
+			Type:      env.Type,
+			RoomID:    env.Room,
+			User:      c.User,
+			MessageID: msg.ID,
+			Emoji:     req.Emoji,
+		})
+	}
+}
+
+func validEmoji(emoji string) bool {
+	return emoji != "" && len(emoji) <= maxEmojiLength && utf8.ValidString(emoji) && !strings.ContainsAny(emoji, " \t\r\n")
+}
+
+// deliverReaction repassa a variação de uma reação às conexões da sala
+func (h *Hub) deliverReaction(event dto.RoomEvent) {
+	frame := encodeEnvelope(event.Type, "", event.RoomID, dto.ReactionChange{
+		MessageID: event.MessageID,
+		Emoji:     event.Emoji,
+		User:      event.User,
+	})
+	for client := range h.Rooms[event.RoomID] {
+		h.deliver(client, frame)
+	}
+}
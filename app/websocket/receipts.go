@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/redis"
+	"github.com/brunobotter/chat-websocket/room"
+)
+
+// handleRead avança o cursor de leitura do usuário na sala e publica o recibo
+// para as conexões da sala em todas as instâncias
+func (c *Client) handleRead(env dto.Envelope) {
+	var req dto.ReadEvent
+	if err := json.Unmarshal(env.Payload, &req); err != nil || req.MessageID == "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid read payload")
+		return
+	}
+
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermReadHistory) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot read the history of "+env.Room)
+		return
+	}
+
+	advanced, err := c.Hub.ChatStore.AdvanceReadCursor(ctx, env.Room, c.User, req.MessageID)
+	if errors.Is(err, redis.ErrInvalidMessageID) {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "invalid message id")
+		return
+	}
+	if err != nil {
+		c.sendError(env.ID, env.Room, ErrCodeUnavailable, "could not save read cursor")
+		return
+	}
+	if advanced {
+		c.publishReceipt(ctx, env.Room, req.MessageID)
+	}
+}
+
+func (c *Client) publishReceipt(ctx context.Context, roomID, messageID string) {
+	_ = c.Hub.publisher.PublishEvent(ctx, "events:"+roomID, dto.RoomEvent{
+		Type:      dto.EventRead,
+		RoomID:    roomID,
+		User:      c.User,
+		MessageID: messageID,
+	})
+}
+
+// deliverReceipt repassa o recibo de leitura às conexões da sala
+func (h *Hub) deliverReceipt(event dto.RoomEvent) {
+	frame := encodeEnvelope(dto.EventRead, "", event.RoomID, dto.ReadEvent{User: event.User, MessageID: event.MessageID})
+	for client := range h.Rooms[event.RoomID] {
+		h.deliver(client, frame)
+	}
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/redis"
+)
+
+// retentionFor retorna a política de histórico da sala, ou a padrão da
+// configuração quando a sala não tem uma própria
+func (h *Hub) retentionFor(ctx context.Context, roomID string) redis.Retention {
+	r, err := h.rooms.GetRoom(ctx, roomID)
+	if err != nil || r.Retention == nil {
+		return h.retention
+	}
+	policy, err := redis.ParseRetention(*r.Retention)
+	if err != nil {
+		return h.retention
+	}
+	return policy
+}
+
+// SweepHistory aplica periodicamente a política de cada sala ao histórico
+// gravado; a idade máxima também é cortada aqui porque o TTL da lista só
+// remove o histórico inteiro de uma sala parada. Só a instância que pega a
+// trava do intervalo varre as salas.
+func (h *Hub) SweepHistory(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := h.ChatStore.AcquireSweepLock(ctx, interval)
+			if err != nil {
+				h.logger.InfoF("Erro ao reservar a varredura de retenção: %v", err)
+				continue
+			}
+			if ok {
+				h.sweepHistory(ctx)
+			}
+		}
+	}
+}
+
+func (h *Hub) sweepHistory(ctx context.Context) {
+	roomIDs, err := h.ChatStore.HistoryRooms(ctx)
+	if err != nil {
+		h.logger.InfoF("Erro ao listar históricos para retenção: %v", err)
+		return
+	}
+	for _, roomID := range roomIDs {
+		removed, err := h.ChatStore.TrimHistory(ctx, roomID, h.retentionFor(ctx, roomID))
+		if err != nil {
+			h.logger.InfoF("Erro ao aplicar retenção na sala %s: %v", roomID, err)
+			continue
+		}
+		if removed > 0 {
+			h.logger.InfoF("Retenção removeu %d mensagens da sala %s", removed, roomID)
+		}
+	}
+}
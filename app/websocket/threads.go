@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/redis"
+)
+
+// handleReply grava a resposta na thread da mensagem reply_to, fora do
+// histórico da sala, e avisa a sala com o novo total de respostas
+func (c *Client) handleReply(ctx context.Context, env dto.Envelope, incoming dto.Incoming, clientMsgID string) {
+	parent, err := c.Hub.ChatStore.GetMessage(ctx, env.Room, incoming.ReplyTo)
+	if errors.Is(err, redis.ErrMessageNotFound) {
+		c.sendError(env.ID, env.Room, ErrCodeNotFound, "message not found in history")
+		return
+	}
+	if err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+	// threads têm um nível só e não existem em mensagens privadas ou apagadas
+	if parent.ParentID != "" || parent.Target != "" || parent.Deleted || incoming.Target != "" {
+		c.sendError(env.ID, env.Room, ErrCodeBadRequest, "cannot reply to this message")
+		return
+	}
+
+	id, err := c.Hub.ChatStore.NextReplyID(ctx, env.Room, parent.ID)
+	if err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+
+	reply := dto.Message{
+		ID:          id,
+		ClientMsgID: clientMsgID,
+		User:        c.User,
+		Content:     incoming.Content,
+		Timestamp:   time.Now(),
+		RoomID:      env.Room,
+		ParentID:    parent.ID,
+	}
+
+	count, err := c.Hub.ChatStore.SaveThreadReply(ctx, env.Room, reply, c.Hub.retentionFor(ctx, env.Room))
+	if err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+	err = c.Hub.publisher.PublishEvent(ctx, "events:"+env.Room, dto.RoomEvent{
+		Type:       dto.EventThreadReply,
+		RoomID:     env.Room,
+		User:       c.User,
+		MessageID:  parent.ID,
+		Message:    &reply,
+		ReplyCount: count,
+	})
+	if err != nil {
+		c.sendDeliveryError(env.ID, env.Room, clientMsgID)
+		return
+	}
+
+	c.sendEvent(dto.EventAck, env.ID, env.Room, dto.AckEvent{
+		ClientMsgID: clientMsgID,
+		MessageID:   reply.ID,
+		Timestamp:   reply.Timestamp,
+	})
+}
+
+// deliverThreadReply avisa as conexões da sala sobre a nova resposta
+func (h *Hub) deliverThreadReply(event dto.RoomEvent) {
+	if event.Message == nil {
+		return
+	}
+	frame := encodeEnvelope(dto.EventThreadReply, event.Message.ID, event.RoomID, dto.ThreadReplyEvent{
+		ParentID:   event.MessageID,
+		ReplyCount: event.ReplyCount,
+		Reply:      *event.Message,
+	})
+	for client := range h.Rooms[event.RoomID] {
+		h.deliver(client, frame)
+	}
+}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/brunobotter/chat-websocket/dto"
+	"github.com/brunobotter/chat-websocket/room"
+)
+
+// typingTTL é quanto um typing.start vale sem ser renovado; o cliente deve
+// reenviar o start enquanto o usuário digita
+const typingTTL = 6 * time.Second
+
+// intervalo de verificação das digitações expiradas
+const typingSweepInterval = time.Second
+
+// handleTyping publica typing.start/typing.stop para a sala em todas as
+// instâncias, sem gravar no histórico
+func (c *Client) handleTyping(env dto.Envelope) {
+	ctx := context.Background()
+	role, ok := c.currentRole(ctx, env)
+	if !ok {
+		return
+	}
+	if !role.Can(room.PermSend) {
+		c.sendError(env.ID, env.Room, ErrCodeForbidden, "you cannot send messages in "+env.Room)
+		return
+	}
+
+	event := dto.RoomEvent{Type: env.Type, RoomID: env.Room, User: c.User}
+	if env.Type == dto.EventTypingStart {
+		event.ExpiresAt = time.Now().Add(typingTTL)
+	}
+	if err := c.Hub.publisher.PublishEvent(ctx, "events:"+env.Room, event); err != nil {
+		c.sendError(env.ID, env.Room, ErrCodeDeliveryFailed, "typing event could not be delivered")
+	}
+}
+
+// typing trata os eventos de digitação recebidos de qualquer instância; cada
+// Hub guarda a expiração para encerrar a digitação se o stop não chegar
+func (h *Hub) typing(event dto.RoomEvent) {
+	switch event.Type {
+	case dto.EventTypingStart:
+		if _, ok := h.typists[event.RoomID]; !ok {
+			h.typists[event.RoomID] = make(map[string]time.Time)
+		}
+		h.typists[event.RoomID][event.User] = event.ExpiresAt
+		h.deliverTyping(event.RoomID, event.User, dto.EventTypingStart, &event.ExpiresAt)
+	case dto.EventTypingStop:
+		if h.stopTyping(event.RoomID, event.User) {
+			h.deliverTyping(event.RoomID, event.User, dto.EventTypingStop, nil)
+		}
+	}
+}
+
+// expireTyping envia typing.stop das digitações que não foram renovadas
+func (h *Hub) expireTyping(now time.Time) {
+	for roomID, typists := range h.typists {
+		for user, expiresAt := range typists {
+			if now.After(expiresAt) {
+				h.stopTyping(roomID, user)
+				h.deliverTyping(roomID, user, dto.EventTypingStop, nil)
+			}
+		}
+	}
+}
+
+// stopTyping retorna false quando o usuário não estava digitando
+func (h *Hub) stopTyping(roomID, user string) bool {
+	typists, ok := h.typists[roomID]
+	if !ok {
+		return false
+	}
+	if _, ok := typists[user]; !ok {
+		return false
+	}
+	delete(typists, user)
+	if len(typists) == 0 {
+		delete(h.typists, roomID)
+	}
+	return true
+}
+
+// deliverTyping entrega o evento às conexões da sala, exceto às do próprio usuário
+func (h *Hub) deliverTyping(roomID, user string, eventType dto.EventType, expiresAt *time.Time) {
+	frame := encodeEnvelope(eventType, "", roomID, dto.TypingEvent{User: user, ExpiresAt: expiresAt})
+	for client := range h.Rooms[roomID] {
+		if client.User != user {
+			h.deliver(client, frame)
+		}
+	}
+}